@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"go_final_project/db"
+	"go_final_project/handlers"
+)
+
+// usage - описание команд командной строки
+const usage = `Использование:
+  go run . [команда]
+
+Без команды запускается веб-сервер.
+
+Команды:
+  backup <файл>   сохранить согласованную копию базы данных
+  restore <файл>  заменить базу данных резервной копией (сервер должен быть остановлен)
+  todotxt export [файл]
+                  выгрузить задачи в формате todo.txt (по умолчанию в стандартный вывод)
+  todotxt import <файл> [--dry-run]
+                  загрузить задачи из todo.txt (--dry-run - только проверить файл)
+  reencrypt       перешифровать поля задач по текущим настройкам шифрования
+                  (после смены ключа или списка шифруемых полей)`
+
+// runCommand выполняет команду командной строки вместо запуска сервера
+func runCommand(dbPath string, args []string) error {
+	switch args[0] {
+	case "backup":
+		if len(args) != 2 {
+			return fmt.Errorf("укажите файл для резервной копии\n%s", usage)
+		}
+		return backupCommand(dbPath, args[1])
+	case "restore":
+		if len(args) != 2 {
+			return fmt.Errorf("укажите файл резервной копии\n%s", usage)
+		}
+		if err := db.RestoreDatabase(dbPath, args[1]); err != nil {
+			return fmt.Errorf("restore failed: %v", err)
+		}
+		log.Printf("Database restored from %s", args[1])
+		return nil
+	case "todotxt":
+		return todoTxtCommand(dbPath, args[1:])
+	case "reencrypt":
+		return reencryptCommand(dbPath)
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("неизвестная команда %q\n%s", args[0], usage)
+	}
+}
+
+// todoTxtCommand выполняет импорт или экспорт задач в формате todo.txt
+func todoTxtCommand(dbPath string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("укажите export или import\n%s", usage)
+	}
+
+	if err := db.SetupDatabase(dbPath); err != nil {
+		return err
+	}
+	if err := setupEncryption(); err != nil {
+		return err
+	}
+	dbConn, err := openDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+	handler := handlers.NewHandler(dbConn)
+
+	switch {
+	case args[0] == "export" && len(args) <= 2:
+		out := os.Stdout
+		if len(args) == 2 {
+			out, err = os.Create(args[1])
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+		return handler.ExportTodoTxt(context.Background(), out)
+	case args[0] == "import" && (len(args) == 2 || len(args) == 3 && args[2] == "--dry-run"):
+		in, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		report, err := handler.ImportTodoTxt(context.Background(), in, "cli", "", len(args) == 3)
+		if err != nil {
+			return err
+		}
+		for _, item := range report.Items {
+			line := fmt.Sprintf("%d: %s %s", item.Line, item.Status, item.Title)
+			if item.ID != "" {
+				line += " (id " + item.ID + ")"
+			}
+			if msg := item.Error + item.Warning; msg != "" {
+				line += " - " + msg
+			}
+			fmt.Println(line)
+		}
+		log.Printf("Imported %d of %d tasks (skipped %d, failed %d)",
+			report.Imported, report.Total, report.Skipped, report.Failed)
+		return nil
+	default:
+		return fmt.Errorf("неверные аргументы команды todotxt\n%s", usage)
+	}
+}
+
+// reencryptCommand приводит шифрование полей в базе к текущим настройкам
+func reencryptCommand(dbPath string) error {
+	if err := db.SetupDatabase(dbPath); err != nil {
+		return err
+	}
+	if err := setupEncryption(); err != nil {
+		return err
+	}
+	dbConn, err := openDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	n, err := db.Reencrypt(context.Background(), dbConn)
+	if err != nil {
+		return fmt.Errorf("reencrypt failed: %v", err)
+	}
+	log.Printf("Re-encrypted %d rows", n)
+	return nil
+}
+
+// setupEncryption включает шифрование полей по переменным окружения
+func setupEncryption() error {
+	cfg, err := db.LoadEncryptionConfig()
+	if err != nil {
+		return err
+	}
+	return db.SetEncryption(cfg)
+}
+
+// openDatabase открывает базу данных с настройками подключения из переменных окружения
+func openDatabase(dbPath string) (*db.DB, error) {
+	cfg, err := db.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return db.Open(dbPath, cfg)
+}
+
+// backupCommand сохраняет копию базы данных в файл dest
+func backupCommand(dbPath, dest string) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("database not found: %v", err)
+	}
+
+	dbConn, err := openDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer dbConn.Close()
+
+	if err := db.Backup(context.Background(), dbConn, dest); err != nil {
+		return err
+	}
+	log.Printf("Backup saved to %s", dest)
+	return nil
+}
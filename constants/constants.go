@@ -2,3 +2,6 @@ package constants
 
 // DateFormat глобальный формат даты (YYYYMMDD)
 const DateFormat = "20060102"
+
+// MaxPriority наибольший приоритет задачи (0 - без приоритета)
+const MaxPriority = 3
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"go_final_project/models"
+)
+
+// AddAttachment сохраняет сведения о вложении и возвращает его ID.
+// data записывается в базу только при хранении вложений в SQLite.
+func AddAttachment(ctx context.Context, db *DB, a models.Attachment, data []byte) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := execContext(ctx, db, `
+		INSERT INTO attachments (task_id, filename, content_type, size, path, data, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		a.TaskID, a.Filename, a.ContentType, a.Size, a.Path, data, a.CreatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetAttachmentByID возвращает сведения о вложении без его содержимого.
+func GetAttachmentByID(ctx context.Context, db *DB, id int) (*models.Attachment, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var a *models.Attachment
+	err := queryRowContext(ctx, db, func(row scanner) error {
+		var err error
+		a, err = scanAttachment(row)
+		return err
+	}, `
+		SELECT id, task_id, filename, content_type, size, path, created_at
+		FROM attachments WHERE id = ?`,
+		id,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("attachment %w", ErrNotFound)
+	}
+	return a, err
+}
+
+// GetAttachmentData возвращает содержимое вложения, хранящегося в SQLite.
+func GetAttachmentData(ctx context.Context, db *DB, id int) ([]byte, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var data []byte
+	err := queryRowContext(ctx, db, func(row scanner) error {
+		return row.Scan(&data)
+	}, "SELECT data FROM attachments WHERE id = ?", id)
+	return data, err
+}
+
+// ListAttachments возвращает вложения задачи.
+func ListAttachments(ctx context.Context, db *DB, taskID int) ([]models.Attachment, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := queryContext(ctx, db, `
+		SELECT id, task_id, filename, content_type, size, path, created_at
+		FROM attachments WHERE task_id = ? ORDER BY id`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+	}
+	return attachments, rows.Err()
+}
+
+// attachmentPathsTx возвращает пути к файлам вложений задачи в рамках транзакции
+func attachmentPathsTx(ctx context.Context, tx *sql.Tx, taskID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT path FROM attachments WHERE task_id = ? AND path != ''", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// DeleteAttachment удаляет сведения о вложении по его ID.
+func DeleteAttachment(ctx context.Context, db *DB, id int) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := execContext(ctx, db, "DELETE FROM attachments WHERE id = ?", id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanAttachment читает сведения о вложении из строки результата запроса.
+func scanAttachment(s scanner) (*models.Attachment, error) {
+	var a models.Attachment
+	var id, taskID int64
+	err := s.Scan(&id, &taskID, &a.Filename, &a.ContentType, &a.Size, &a.Path, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	a.ID = strconv.FormatInt(id, 10)
+	a.TaskID = strconv.FormatInt(taskID, 10)
+	return &a, nil
+}
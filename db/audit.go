@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"go_final_project/models"
+)
+
+// AddAuditRecord сохраняет запись журнала изменений и возвращает её ID.
+func AddAuditRecord(ctx context.Context, db *DB, rec models.AuditRecord) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := db.retryBusy(ctx, func() error {
+		var err error
+		id, err = addAuditRecord(ctx, db, rec)
+		return err
+	})
+	return id, err
+}
+
+// addAuditRecord сохраняет запись журнала через переданное подключение или транзакцию.
+func addAuditRecord(ctx context.Context, ex execer, rec models.AuditRecord) (int64, error) {
+	query := `
+		INSERT INTO audit_log (task_id, action, actor, before, after, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	before, err := encryptAudit(rec.Before)
+	if err != nil {
+		return 0, err
+	}
+	after, err := encryptAudit(rec.After)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := ex.ExecContext(ctx, query, rec.TaskID, rec.Action, rec.Actor, before, after, rec.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to insert audit record: %v", err)
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetAuditRecords возвращает записи журнала, начиная с самых новых.
+// Если taskID равен 0, возвращаются записи по всем задачам.
+func GetAuditRecords(ctx context.Context, db *DB, taskID int, limit int) ([]models.AuditRecord, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, task_id, action, actor, before, after, created_at FROM audit_log
+		WHERE ? = 0 OR task_id = ?
+		ORDER BY id DESC LIMIT ?
+	`
+	rows, err := queryContext(ctx, db, query, taskID, taskID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.AuditRecord{}
+	for rows.Next() {
+		rec, err := scanAuditRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, *rec)
+	}
+	return records, rows.Err()
+}
+
+// GetAuditRecordByID возвращает запись журнала по её ID.
+func GetAuditRecordByID(ctx context.Context, db *DB, id int) (*models.AuditRecord, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var rec *models.AuditRecord
+	err := queryRowContext(ctx, db, func(row scanner) error {
+		var err error
+		rec, err = scanAuditRecord(row)
+		return err
+	}, "SELECT id, task_id, action, actor, before, after, created_at FROM audit_log WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("audit record %w", ErrNotFound)
+	}
+	return rec, err
+}
+
+// auditRemovedTasks в рамках транзакции записывает в журнал удаление задач,
+// подходящих под фильтр, по образцу записи audit и возвращает пути к файлам их вложений.
+// Задачи удаляет вызывающий в той же транзакции; файлы удаляются после её фиксации.
+func auditRemovedTasks(ctx context.Context, tx *sql.Tx, filter TaskFilter, audit models.AuditRecord) ([]string, error) {
+	tasks, err := listTasksTx(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, task := range tasks {
+		rec := audit
+		rec.TaskID = task.ID
+		task.Checklist = nil
+		if rec.Before, err = models.MarshalTaskSnapshot(task); err != nil {
+			return nil, err
+		}
+		if _, err := addAuditRecord(ctx, tx, rec); err != nil {
+			return nil, err
+		}
+
+		taskPaths, err := attachmentPathsTx(ctx, tx, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, taskPaths...)
+	}
+	return paths, nil
+}
+
+// lastAuditVersion возвращает версию задачи из последней записи журнала о ней
+// (наибольшую из снимков до и после изменения) или 0, если записей нет.
+func lastAuditVersion(ctx context.Context, tx *sql.Tx, taskID string) (int, error) {
+	row := tx.QueryRowContext(ctx,
+		"SELECT id, task_id, action, actor, before, after, created_at FROM audit_log WHERE task_id = ? ORDER BY id DESC LIMIT 1",
+		taskID,
+	)
+	rec, err := scanAuditRecord(row)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var version int
+	for _, data := range [][]byte{rec.Before, rec.After} {
+		var snapshot models.TaskSnapshot
+		if len(data) > 0 && json.Unmarshal(data, &snapshot) == nil {
+			version = max(version, snapshot.Version)
+		}
+	}
+	return version, nil
+}
+
+// scanner - общий интерфейс для *sql.Row и *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// execer - общий интерфейс для *sql.DB и *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// scanAuditRecord читает запись журнала из строки результата запроса.
+func scanAuditRecord(s scanner) (*models.AuditRecord, error) {
+	var rec models.AuditRecord
+	var id, taskID int64
+	var before, after sql.NullString
+	err := s.Scan(&id, &taskID, &rec.Action, &rec.Actor, &before, &after, &rec.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rec.ID = strconv.FormatInt(id, 10)
+	rec.TaskID = strconv.FormatInt(taskID, 10)
+	rec.Before = decryptSnapshot(rec.ID, before)
+	rec.After = decryptSnapshot(rec.ID, after)
+	return &rec, nil
+}
+
+// decryptSnapshot расшифровывает снимок задачи из записи журнала recID.
+// Снимок, который нельзя расшифровать, пропускается с записью в лог,
+// чтобы не ломать весь журнал.
+func decryptSnapshot(recID string, stored sql.NullString) []byte {
+	if !stored.Valid {
+		return nil
+	}
+	data, err := decryptValue(auditField, stored.String)
+	if err != nil {
+		log.Printf("[WARN] audit record %s: snapshot is not decrypted: %v", recID, err)
+		return nil
+	}
+	return []byte(data)
+}
+
+// encryptAudit возвращает снимок задачи для записи в журнал: при включённом
+// шифровании снимок шифруется целиком, так как содержит заголовок и комментарий.
+func encryptAudit(data []byte) (any, error) {
+	if fieldCipher == nil || len(data) == 0 {
+		return nullableJSON(data), nil
+	}
+	return encryptValue(auditField, string(data))
+}
+
+// nullableJSON превращает пустое состояние задачи в NULL.
+func nullableJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// SchemaVersion возвращает версию схемы, которую поддерживает приложение.
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// Backup сохраняет согласованный снимок базы данных в файл dest с помощью VACUUM INTO.
+// Снимок делается внутри одной транзакции чтения, поэтому его можно снимать
+// во время работы сервера. Файл dest не должен существовать.
+func Backup(ctx context.Context, db *DB, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup file already exists: %s", dest)
+	}
+	if _, err := execContext(ctx, db, "VACUUM INTO ?", dest); err != nil {
+		return fmt.Errorf("failed to create backup: %v", err)
+	}
+	return nil
+}
+
+// uriPathEscaper экранирует символы, которые в URI SQLite отделяют путь к файлу от параметров
+var uriPathEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+// ValidateBackup проверяет, что файл является целой базой данных планировщика
+// со схемой не новее поддерживаемой, и возвращает версию его схемы.
+func ValidateBackup(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+
+	// Параметр mode учитывается только в URI (file:), иначе файл открывается на запись
+	db, err := sql.Open("sqlite", "file:"+uriPathEscaper.Replace(path)+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var integrity string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&integrity); err != nil {
+		return 0, fmt.Errorf("not a valid database: %v", err)
+	}
+	if integrity != "ok" {
+		return 0, fmt.Errorf("integrity check failed: %s", integrity)
+	}
+
+	var tables int
+	err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'scheduler'").Scan(&tables)
+	if err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return 0, fmt.Errorf("table 'scheduler' not found")
+	}
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, err
+	}
+	if version > SchemaVersion() {
+		return version, fmt.Errorf("backup schema version %d is newer than supported %d",
+			version, SchemaVersion())
+	}
+	return version, nil
+}
+
+// RestoreDatabase заменяет файл базы данных резервной копией.
+// Сервер на время восстановления должен быть остановлен. Текущая база сохраняется
+// рядом с суффиксом .before-restore-<время>, а недостающие миграции применяются
+// при следующем запуске.
+func RestoreDatabase(dbPath, backupPath string) error {
+	version, err := ValidateBackup(backupPath)
+	if err != nil {
+		return err
+	}
+	log.Printf("Backup is valid, schema version %d", version)
+
+	// Копируем резервную копию рядом с базой, чтобы замена была атомарным переименованием
+	tmpPath := dbPath + ".restore"
+	if err := copyFile(backupPath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// Журналы старой базы переносятся вместе с ней: в -wal могут быть изменения,
+	// ещё не перенесённые в основной файл, и они не должны примениться к восстановленной базе
+	oldPath := dbPath + ".before-restore-" + time.Now().Format("20060102150405")
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		if _, err := os.Stat(dbPath + suffix); err != nil {
+			continue
+		}
+		if err := os.Rename(dbPath+suffix, oldPath+suffix); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		if suffix == "" {
+			log.Printf("Current database saved as %s", oldPath)
+		}
+	}
+
+	return os.Rename(tmpPath, dbPath)
+}
+
+// copyFile копирует файл src в dst с записью на диск.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,293 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go_final_project/models"
+)
+
+// Действия пакетного запроса
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+	BatchDone   = "done"
+	// BatchRestore записывает задачу с сохранением ID (возврат по журналу);
+	// в пакетном запросе не используется
+	BatchRestore = "restore"
+)
+
+// ErrBatchAborted возвращается, если в режиме "всё или ничего" одна из операций
+// не выполнилась и транзакция откатена
+var ErrBatchAborted = errors.New("batch aborted")
+
+// BatchOp - проверенная операция пакетного запроса.
+// Для create Task - новая задача, для update и restore - итоговое состояние задачи,
+// для delete и done достаточно ID. Текущее состояние задачи читается
+// в транзакции непосредственно перед операцией, поэтому учитывает предыдущие
+// операции пакета. Непустая версия задачи (Task.Version) должна совпасть
+// с версией в базе.
+type BatchOp struct {
+	Action string
+	Task   models.Task
+	// SetTags заменяет теги задачи на Task.Tags (create и update);
+	// иначе update оставляет прежние теги
+	SetTags bool
+	// KeepPriority и KeepProject оставляют при update прежние приоритет и проект
+	// задачи: поля не переданы в запросе
+	KeepPriority bool
+	KeepProject  bool
+	// Audit - запись журнала, которая сохраняется в той же транзакции.
+	// Снимки задачи до и после изменения заполняются по результату операции.
+	Audit *models.AuditRecord
+}
+
+// BatchResult - результат операции пакетного запроса
+type BatchResult struct {
+	// ID - идентификатор созданной задачи (create)
+	ID int64
+	// Before - состояние задачи перед update, delete или done
+	Before *models.Task
+	// Task - состояние задачи после update, done (nil, если задача удалена) или restore
+	Task *models.Task
+	// Paths - файлы вложений удалённой задачи; удаляются после фиксации транзакции
+	Paths []string
+	Err   error
+}
+
+// ExecBatch выполняет операции в одной транзакции и возвращает результат каждой.
+// Если atomic, первая ошибка откатывает транзакцию, а ExecBatch возвращает ErrBatchAborted
+// и результаты до неудачной операции включительно. Иначе каждая операция выполняется
+// в своей точке сохранения: ошибка откатывает только её, остальные фиксируются.
+// Ошибка возвращается только если транзакцию не удалось начать или зафиксировать.
+func ExecBatch(ctx context.Context, db *DB, ops []BatchOp, atomic bool, now time.Time) ([]BatchResult, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]BatchResult, 0, len(ops))
+	for i, op := range ops {
+		savepoint := "batch_op_" + strconv.Itoa(i)
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+				return nil, err
+			}
+		}
+
+		result := execBatchOp(ctx, tx, op, now)
+		if result.Err == nil {
+			result.Err = addOpAudit(ctx, tx, op, result)
+		}
+		results = append(results, result)
+
+		if result.Err != nil {
+			if atomic {
+				return results, ErrBatchAborted
+			}
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO "+savepoint); err != nil {
+				return nil, err
+			}
+		}
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, "RELEASE "+savepoint); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ExecTaskOp выполняет одну операцию с задачей вместе с тегами и записью журнала
+// в одной транзакции, поэтому изменение не может сохраниться без записи журнала.
+// Если задача удалена или её версия изменилась, возвращается ErrTaskConflict.
+func ExecTaskOp(ctx context.Context, db *DB, op BatchOp, now time.Time) (BatchResult, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return BatchResult{}, err
+	}
+	defer tx.Rollback()
+
+	result := execBatchOp(ctx, tx, op, now)
+	if result.Err != nil {
+		return result, result.Err
+	}
+	if err := addOpAudit(ctx, tx, op, result); err != nil {
+		return result, err
+	}
+	return result, tx.Commit()
+}
+
+// addOpAudit сохраняет запись журнала выполненной операции в её транзакции,
+// дополняя запись состоянием задачи, известным только после выполнения
+func addOpAudit(ctx context.Context, tx *sql.Tx, op BatchOp, result BatchResult) error {
+	if op.Audit == nil {
+		return nil
+	}
+
+	rec := *op.Audit
+	var err error
+	if result.Before != nil {
+		if rec.Before, err = models.MarshalTaskSnapshot(*result.Before); err != nil {
+			return err
+		}
+	}
+	switch op.Action {
+	case BatchCreate:
+		task := op.Task
+		task.ID = strconv.FormatInt(result.ID, 10)
+		task.Version = 1
+		rec.TaskID = task.ID
+		rec.After, err = models.MarshalTaskSnapshot(task)
+	case BatchUpdate, BatchDone, BatchRestore:
+		if result.Task != nil {
+			rec.After, err = models.MarshalTaskSnapshot(*result.Task)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = addAuditRecord(ctx, tx, rec)
+	return err
+}
+
+// execBatchOp выполняет одну операцию пакетного запроса в транзакции
+func execBatchOp(ctx context.Context, tx *sql.Tx, op BatchOp, now time.Time) BatchResult {
+	var result BatchResult
+	switch op.Action {
+	case BatchCreate:
+		result.ID, result.Err = addTask(ctx, tx, op.Task)
+		if result.Err == nil && op.SetTags {
+			result.Err = setTaskTags(ctx, tx, result.ID, op.Task.Tags)
+		}
+	case BatchUpdate:
+		result.Err = execUpdate(ctx, tx, op, &result)
+	case BatchDelete:
+		result.Err = execDelete(ctx, tx, op, &result)
+	case BatchDone:
+		if result.Before, result.Err = currentTask(ctx, tx, op.Task); result.Err != nil {
+			break
+		}
+		// Одноразовая задача при завершении удаляется вместе с вложениями
+		if result.Before.Repeat == "" {
+			if result.Paths, result.Err = attachmentPathsTx(ctx, tx, result.Before.ID); result.Err != nil {
+				break
+			}
+		}
+		result.Task, result.Err = completeTask(ctx, tx, *result.Before, now)
+	case BatchRestore:
+		result.Task, result.Err = restoreTask(ctx, tx, op.Task)
+		if result.Err == nil && op.SetTags {
+			id, _ := strconv.ParseInt(op.Task.ID, 10, 64)
+			result.Err = setTaskTags(ctx, tx, id, op.Task.Tags)
+		}
+	default:
+		result.Err = fmt.Errorf("unknown batch action %q", op.Action)
+	}
+	return result
+}
+
+// currentTask читает в транзакции текущее состояние задачи task.ID и сверяет
+// его с ожидаемой версией task.Version (0 - без проверки). Отсутствие задачи,
+// как и несовпадение версии, - конфликт с параллельным изменением, но ошибка
+// сообщает и о том, что задача не найдена.
+func currentTask(ctx context.Context, tx *sql.Tx, task models.Task) (*models.Task, error) {
+	id, err := strconv.Atoi(task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: task %w", ErrTaskConflict, ErrNotFound)
+	}
+	current, err := getTaskTx(ctx, tx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrTaskConflict, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if task.Version != 0 && task.Version != current.Version {
+		return nil, ErrTaskConflict
+	}
+	return current, nil
+}
+
+// execUpdate обновляет задачу, дополняя op.Task прежними значениями полей,
+// которые не переданы в запросе. Проект проверяется, только если он меняется.
+func execUpdate(ctx context.Context, tx *sql.Tx, op BatchOp, result *BatchResult) error {
+	before, err := currentTask(ctx, tx, op.Task)
+	if err != nil {
+		return err
+	}
+
+	task := op.Task
+	task.CreatedAt = before.CreatedAt
+	if op.KeepPriority {
+		task.Priority = before.Priority
+	}
+	if op.KeepProject {
+		task.ProjectID = before.ProjectID
+	} else if task.ProjectID != before.ProjectID {
+		if err := checkProject(ctx, tx, task.ProjectID); err != nil {
+			return err
+		}
+	}
+	if !op.SetTags {
+		task.Tags = before.Tags
+	}
+
+	task.Version = before.Version
+	if err := requireRow(updateTask(ctx, tx, task)); err != nil {
+		return err
+	}
+	if op.SetTags {
+		id, _ := strconv.ParseInt(task.ID, 10, 64)
+		if err := setTaskTags(ctx, tx, id, task.Tags); err != nil {
+			return err
+		}
+	}
+	task.Version++
+	result.Before, result.Task = before, &task
+	return nil
+}
+
+// execDelete удаляет задачу и запоминает пути к файлам её вложений
+func execDelete(ctx context.Context, tx *sql.Tx, op BatchOp, result *BatchResult) error {
+	before, err := currentTask(ctx, tx, op.Task)
+	if err != nil {
+		return err
+	}
+	paths, err := attachmentPathsTx(ctx, tx, before.ID)
+	if err != nil {
+		return err
+	}
+	id, _ := strconv.Atoi(before.ID)
+	if err := requireRow(deleteTask(ctx, tx, id, before.Version)); err != nil {
+		return err
+	}
+	result.Before, result.Paths = before, paths
+	return nil
+}
+
+// requireRow превращает отсутствие изменённых строк в ErrTaskConflict:
+// задача удалена или её версия изменилась после проверки
+func requireRow(rowsAffected int64, err error) error {
+	if err == nil && rowsAffected == 0 {
+		return ErrTaskConflict
+	}
+	return err
+}
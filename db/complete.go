@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_final_project/models"
+	"go_final_project/utils"
+)
+
+// ErrTaskConflict возвращается, если задача была изменена параллельным запросом
+var ErrTaskConflict = errors.New("task was modified concurrently")
+
+// ErrStoredRepeatInvalid возвращается, если для сохранённого ранее правила
+// повторения задачи нельзя вычислить следующую дату
+var ErrStoredRepeatInvalid = errors.New("stored repeat rule is invalid")
+
+// completeTask отмечает задачу выполненной в рамках переданной транзакции:
+// одноразовая задача удаляется, а повторяющаяся переносится на следующую дату
+// со сбросом подзадач. Изменение применяется, только если версия задачи совпадает
+// с task.Version, поэтому из нескольких параллельных запросов задачу продвигает
+// только один, а остальные получают ErrTaskConflict.
+// Возвращает обновлённую задачу или nil, если задача удалена.
+func completeTask(ctx context.Context, ex execer, task models.Task, now time.Time) (*models.Task, error) {
+	var nextDate string
+	if task.Repeat != "" {
+		var err error
+		nextDate, err = utils.NextDate(now, task.Date, task.Repeat)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStoredRepeatInvalid, err)
+		}
+	}
+
+	var result sql.Result
+	var err error
+	if task.Repeat == "" {
+		result, err = ex.ExecContext(ctx,
+			"DELETE FROM scheduler WHERE id = ? AND version = ?",
+			task.ID, task.Version,
+		)
+	} else {
+		result, err = ex.ExecContext(ctx,
+			"UPDATE scheduler SET date = ?, version = version + 1 WHERE id = ? AND version = ?",
+			nextDate, task.ID, task.Version,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrTaskConflict
+	}
+
+	if task.Repeat == "" {
+		return nil, nil
+	}
+
+	// Новое повторение начинается с пустого чек-листа
+	if _, err := ex.ExecContext(ctx, "UPDATE subtasks SET done = 0 WHERE task_id = ?", task.ID); err != nil {
+		return nil, err
+	}
+
+	task.Date = nextDate
+	task.Version++
+	return &task, nil
+}
@@ -0,0 +1,401 @@
+package db
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go_final_project/models"
+)
+
+// encryptedPrefix отмечает зашифрованные значения: enc:v1:<ID ключа>:<base64(nonce+шифротекст)>
+const encryptedPrefix = "enc:v1:"
+
+// reservedPrefix - начало служебных значений. Незашифрованное значение с таким
+// началом хранится с префиксом plainPrefix, чтобы его не приняли за шифротекст.
+const (
+	reservedPrefix = "enc:"
+	plainPrefix    = "enc:plain:"
+)
+
+// Поля, которые можно шифровать
+const (
+	FieldComment = "comment"
+	FieldTitle   = "title"
+)
+
+// ErrNoEncryptionKey возвращается при чтении зашифрованного значения без подходящего ключа
+var ErrNoEncryptionKey = errors.New("encryption key for stored value is not configured")
+
+// EncryptionConfig задаёт шифрование полей задач.
+// Новые значения шифруются ключом Key, OldKeys нужны только для чтения
+// значений, зашифрованных до смены ключа (см. Reencrypt).
+type EncryptionConfig struct {
+	Key     []byte
+	OldKeys [][]byte
+	// Fields - шифруемые поля задачи (FieldComment, FieldTitle)
+	Fields []string
+}
+
+// fieldCipher - текущие настройки шифрования; nil, если шифрование выключено
+var fieldCipher *cipherSet
+
+type cipherSet struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+	fields    map[string]bool
+}
+
+// SetEncryption включает шифрование полей задач (AES-256-GCM) для всех функций пакета.
+// nil выключает шифрование; уже зашифрованные значения при этом перестают читаться.
+// Вызывается один раз при запуске, до обработки запросов.
+func SetEncryption(cfg *EncryptionConfig) error {
+	if cfg == nil {
+		fieldCipher = nil
+		return nil
+	}
+
+	set := &cipherSet{keys: make(map[string]cipher.AEAD), fields: make(map[string]bool)}
+	for i, key := range append([][]byte{cfg.Key}, cfg.OldKeys...) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("invalid encryption key: %v", err)
+		}
+		if len(key) != 32 {
+			return errors.New("invalid encryption key: AES-256 requires 32 bytes")
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		id := keyID(key)
+		if i == 0 {
+			set.currentID = id
+		}
+		set.keys[id] = aead
+	}
+
+	for _, field := range cfg.Fields {
+		if field != FieldComment && field != FieldTitle {
+			return fmt.Errorf("field %q cannot be encrypted", field)
+		}
+		set.fields[field] = true
+	}
+
+	fieldCipher = set
+	return nil
+}
+
+// LoadEncryptionConfig читает настройки шифрования из переменных окружения:
+// TODO_ENCRYPTION_KEY или TODO_ENCRYPTION_KEY_FILE - ключ (32 байта в base64 или hex),
+// TODO_ENCRYPTION_OLD_KEYS - прежние ключи через запятую,
+// TODO_ENCRYPT_FIELDS - шифруемые поля через запятую (по умолчанию comment).
+// Возвращает nil, если ключ не задан.
+func LoadEncryptionConfig() (*EncryptionConfig, error) {
+	value := os.Getenv("TODO_ENCRYPTION_KEY")
+	if file := os.Getenv("TODO_ENCRYPTION_KEY_FILE"); file != "" {
+		if value != "" {
+			return nil, errors.New("TODO_ENCRYPTION_KEY and TODO_ENCRYPTION_KEY_FILE are mutually exclusive")
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %v", err)
+		}
+		value = string(data)
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var cfg EncryptionConfig
+	var err error
+	if cfg.Key, err = parseKey(value); err != nil {
+		return nil, err
+	}
+	if old := os.Getenv("TODO_ENCRYPTION_OLD_KEYS"); old != "" {
+		for _, value := range strings.Split(old, ",") {
+			key, err := parseKey(value)
+			if err != nil {
+				return nil, err
+			}
+			cfg.OldKeys = append(cfg.OldKeys, key)
+		}
+	}
+
+	cfg.Fields = []string{FieldComment}
+	if fields := os.Getenv("TODO_ENCRYPT_FIELDS"); fields != "" {
+		cfg.Fields = nil
+		for _, field := range strings.Split(fields, ",") {
+			cfg.Fields = append(cfg.Fields, strings.TrimSpace(field))
+		}
+	}
+	return &cfg, nil
+}
+
+// parseKey разбирает ключ длиной 32 байта, записанный в hex или base64
+func parseKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if key, err := hex.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, errors.New("encryption key must be 32 bytes encoded as hex or base64")
+}
+
+// keyID - короткий идентификатор ключа, по которому при чтении выбирается ключ
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// encryptValue шифрует значение поля текущим ключом. Имя поля участвует
+// в шифровании как дополнительные данные, поэтому значения нельзя переставить
+// между полями. Пустые строки не шифруются.
+func encryptValue(field, value string) (string, error) {
+	if fieldCipher == nil || value == "" {
+		return escapePlain(value), nil
+	}
+
+	aead := fieldCipher.keys[fieldCipher.currentID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(field))
+	return encryptedPrefix + fieldCipher.currentID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue расшифровывает значение поля; незашифрованные значения возвращаются как есть
+func decryptValue(field, value string) (string, error) {
+	if strings.HasPrefix(value, plainPrefix) {
+		return strings.TrimPrefix(value, plainPrefix), nil
+	}
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, nil
+	}
+	if fieldCipher == nil {
+		return "", ErrNoEncryptionKey
+	}
+
+	id, data, ok := strings.Cut(strings.TrimPrefix(value, encryptedPrefix), ":")
+	aead, found := fieldCipher.keys[id]
+	if !ok || !found {
+		return "", ErrNoEncryptionKey
+	}
+	sealed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %v", field, err)
+	}
+	return string(plain), nil
+}
+
+// escapePlain отмечает незашифрованное значение, которое начинается как служебное
+func escapePlain(value string) string {
+	if strings.HasPrefix(value, reservedPrefix) {
+		return plainPrefix + value
+	}
+	return value
+}
+
+// encryptField шифрует значение, только если поле выбрано для шифрования
+func encryptField(field, value string) (string, error) {
+	if fieldCipher == nil || !fieldCipher.fields[field] {
+		return escapePlain(value), nil
+	}
+	return encryptValue(field, value)
+}
+
+// encryptTask возвращает заголовок и комментарий задачи в том виде, в каком они хранятся в базе
+func encryptTask(task models.Task) (title, comment string, err error) {
+	if title, err = encryptField(FieldTitle, task.Title); err != nil {
+		return "", "", err
+	}
+	if comment, err = encryptField(FieldComment, task.Comment); err != nil {
+		return "", "", err
+	}
+	return title, comment, nil
+}
+
+// decryptTask расшифровывает заголовок и комментарий прочитанной задачи.
+// Значение, которое нельзя расшифровать (например, ключ не задан), остаётся
+// в хранимом виде: одна такая задача не должна ломать весь список.
+func decryptTask(task *models.Task) {
+	task.Title = decryptStored(FieldTitle, task.ID, task.Title)
+	task.Comment = decryptStored(FieldComment, task.ID, task.Comment)
+}
+
+// decryptStored расшифровывает значение поля задачи taskID, а при ошибке
+// записывает её в лог и возвращает значение в хранимом виде
+func decryptStored(field, taskID, value string) string {
+	plain, err := decryptValue(field, value)
+	if err != nil {
+		log.Printf("[WARN] task %s: %s is not decrypted: %v", taskID, field, err)
+		return value
+	}
+	return plain
+}
+
+// auditField - имя, под которым шифруются снимки задачи в журнале изменений
+const auditField = "audit"
+
+// reencryptValue приводит хранимое значение поля к текущим настройкам шифрования.
+// Второе значение сообщает, нужно ли перезаписать поле.
+func reencryptValue(field, stored string, encrypt bool) (string, bool, error) {
+	encrypted := strings.HasPrefix(stored, encryptedPrefix)
+	if encrypt && stored != "" && encrypted &&
+		strings.HasPrefix(stored, encryptedPrefix+fieldCipher.currentID+":") {
+		return stored, false, nil
+	}
+	if !encrypt && !encrypted {
+		return stored, false, nil
+	}
+
+	value, err := decryptValue(field, stored)
+	if err != nil {
+		return "", false, err
+	}
+	if encrypt {
+		value, err = encryptValue(field, value)
+	}
+	return value, value != stored, err
+}
+
+// Reencrypt перезаписывает заголовки, комментарии и снимки журнала по текущим настройкам:
+// значения, зашифрованные прежним ключом, шифруются текущим, незашифрованные поля,
+// выбранные для шифрования, шифруются, а остальные расшифровываются.
+// Для смены ключа новый ключ задаётся основным, а прежний - в OldKeys.
+// Прежние значения не должны остаться в файле базы: перезапись идёт с secure_delete,
+// полнотекстовый индекс перестраивается, а после фиксации база сжимается VACUUM.
+// Возвращает количество изменённых строк.
+func Reencrypt(ctx context.Context, db *DB) (int, error) {
+	// secure_delete действует на соединение, поэтому вся работа идёт через одно
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA secure_delete = ON"); err != nil {
+		return 0, err
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "PRAGMA secure_delete = OFF")
+
+	changed, err := reencryptTables(ctx, db, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	// VACUUM переписывает файл без освободившихся страниц, а контрольная точка
+	// переносит результат из журнала WAL в основной файл и очищает журнал
+	if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+		return changed, err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return changed, err
+	}
+	return changed, nil
+}
+
+// reencryptTables перешифровывает задачи и журнал в одной транзакции
+// и перестраивает полнотекстовый индекс, чтобы в нём не осталось прежних записей
+func reencryptTables(ctx context.Context, db *DB, conn *sql.Conn) (int, error) {
+	var tx *sql.Tx
+	err := db.retryBusy(ctx, func() error {
+		var err error
+		tx, err = conn.BeginTx(ctx, nil)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	changed, err := reencryptColumns(ctx, tx, "scheduler", FieldTitle, FieldComment)
+	if err != nil {
+		return 0, err
+	}
+	n, err := reencryptColumns(ctx, tx, "audit_log", "before", "after")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild')"); err != nil {
+		return 0, err
+	}
+	return changed + n, tx.Commit()
+}
+
+// reencryptColumns перешифровывает два столбца таблицы. Столбцы журнала
+// (before, after) шифруются целиком при любых включённых полях.
+func reencryptColumns(ctx context.Context, tx *sql.Tx, table, first, second string) (int, error) {
+	type row struct {
+		id     int64
+		values [2]sql.NullString
+	}
+
+	// Сначала читаем все строки, затем обновляем их в той же транзакции
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT id, %s, %s FROM %s", first, second, table))
+	if err != nil {
+		return 0, err
+	}
+	var stored []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.values[0], &r.values[1]); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stored = append(stored, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	columns := [2]string{first, second}
+	var changed int
+	for _, r := range stored {
+		var update bool
+		for i, column := range columns {
+			if !r.values[i].Valid {
+				continue
+			}
+			field, encrypt := column, fieldCipher != nil && fieldCipher.fields[column]
+			if table == "audit_log" {
+				field, encrypt = auditField, fieldCipher != nil
+			}
+			value, rewrite, err := reencryptValue(field, r.values[i].String, encrypt)
+			if err != nil {
+				return 0, fmt.Errorf("%s %d: %v", table, r.id, err)
+			}
+			r.values[i].String = value
+			update = update || rewrite
+		}
+		if !update {
+			continue
+		}
+
+		// Содержимое не меняется, поэтому версия задачи не увеличивается
+		query := fmt.Sprintf("UPDATE %s SET %s = ?, %s = ? WHERE id = ?", table, first, second)
+		if _, err := tx.ExecContext(ctx, query, r.values[0], r.values[1], r.id); err != nil {
+			return 0, err
+		}
+		changed++
+	}
+	return changed, nil
+}
@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"go_final_project/models"
 	"log"
@@ -12,6 +14,9 @@ import (
 	_ "modernc.org/sqlite" // Подключаем SQLite без CGO
 )
 
+// ErrNotFound возвращается, если запрошенная запись отсутствует в базе
+var ErrNotFound = errors.New("not found")
+
 // GetDatabasePath возвращает путь к файлу базы данных.
 // Если переменная TODO_DBFILE задана, используется её значение. (Задача со звёздочкой)
 func GetDatabasePath() string {
@@ -26,7 +31,8 @@ func GetDatabasePath() string {
 	return dbPath
 }
 
-// SetupDatabase проверяет наличие файла базы данных и создаёт таблицу, если её нет.
+// SetupDatabase проверяет наличие файла базы данных, создаёт таблицу, если её нет,
+// и применяет недостающие миграции.
 func SetupDatabase(dbFile string) error {
 	_, err := os.Stat(dbFile)
 	var install bool
@@ -53,7 +59,7 @@ func SetupDatabase(dbFile string) error {
 			return err
 		}
 	}
-	return nil
+	return migrate(db)
 }
 
 // createTable создаёт таблицу и индекс по полю date.
@@ -79,12 +85,32 @@ func createTable(db *sql.DB) error {
 }
 
 // AddTask добавляет новую задачу в таблицу scheduler и возвращает её ID.
-func AddTask(db *sql.DB, date, title, comment, repeat string) (int64, error) {
+func AddTask(ctx context.Context, db *DB, task models.Task) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := db.retryBusy(ctx, func() error {
+		var err error
+		id, err = addTask(ctx, db, task)
+		return err
+	})
+	return id, err
+}
+
+// addTask добавляет задачу через переданное подключение или транзакцию.
+func addTask(ctx context.Context, ex execer, task models.Task) (int64, error) {
+	title, comment, err := encryptTask(task)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-		INSERT INTO scheduler (date, title, comment, repeat)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO scheduler (date, title, comment, repeat, project_id, priority, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
-	res, err := db.Exec(query, date, title, comment, repeat)
+	res, err := ex.ExecContext(ctx, query, task.Date, title, comment, task.Repeat,
+		nullableID(task.ProjectID), task.Priority, task.CreatedAt)
 	if err != nil {
 		log.Printf("Failed to insert task: %v", err)
 		return 0, err
@@ -100,33 +126,76 @@ func AddTask(db *sql.DB, date, title, comment, repeat string) (int64, error) {
 }
 
 // GetTaskByID возвращает данные задачи по её ID.
-func GetTaskByID(db *sql.DB, id int) (*models.Task, error) {
-	var task models.Task
-	row := db.QueryRow(
-		"SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?",
-		id,
-	)
+func GetTaskByID(ctx context.Context, db *DB, id int) (*models.Task, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
 
+	var task models.Task
 	var taskID int64
-	err := row.Scan(&taskID, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	var projectID sql.NullInt64
+	err := queryRowContext(ctx, db, func(row scanner) error {
+		return row.Scan(&taskID, &task.Date, &task.Title, &task.Comment, &task.Repeat, &projectID,
+			&task.Priority, &task.CreatedAt, &task.Version)
+	}, `SELECT id, date, title, comment, repeat, project_id, priority, created_at, version
+		FROM scheduler WHERE id = ?`, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("task not found")
+			return nil, fmt.Errorf("task %w", ErrNotFound)
 		}
 		return nil, err
 	}
 
 	task.ID = strconv.FormatInt(taskID, 10)
+	decryptTask(&task)
+	task.ProjectID = formatNullID(projectID)
+	task.Tags, err = GetTaskTags(ctx, db, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// getTaskTx возвращает данные задачи по её ID в рамках транзакции.
+func getTaskTx(ctx context.Context, tx *sql.Tx, id int) (*models.Task, error) {
+	var task models.Task
+	var taskID int64
+	var projectID sql.NullInt64
+	err := tx.QueryRowContext(ctx, `SELECT id, date, title, comment, repeat, project_id, priority, created_at, version
+		FROM scheduler WHERE id = ?`, id).Scan(&taskID, &task.Date, &task.Title, &task.Comment, &task.Repeat,
+		&projectID, &task.Priority, &task.CreatedAt, &task.Version)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("task %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	task.ID = strconv.FormatInt(taskID, 10)
+	decryptTask(&task)
+	task.ProjectID = formatNullID(projectID)
+	task.Tags, err = taskTagsTx(ctx, tx, taskID)
+	if err != nil {
+		return nil, err
+	}
 	return &task, nil
 }
 
-// UpdateTask обновляет данные задачи.
-func UpdateTask(db *sql.DB, task models.Task) (int64, error) {
+// updateTask обновляет данные задачи через переданное подключение или транзакцию
+// и увеличивает её версию. Если task.Version не равна 0, задача обновляется
+// только при совпадении версии, иначе возвращается 0 изменённых строк.
+func updateTask(ctx context.Context, ex execer, task models.Task) (int64, error) {
+	title, comment, err := encryptTask(task)
+	if err != nil {
+		return 0, err
+	}
+
 	query := `
-		UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?
-		WHERE id = ?
+		UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?, project_id = ?, priority = ?,
+			version = version + 1
+		WHERE id = ? AND (? = 0 OR version = ?)
 	`
-	result, err := db.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
+	result, err := ex.ExecContext(ctx, query, task.Date, title, comment, task.Repeat,
+		nullableID(task.ProjectID), task.Priority, task.ID, task.Version, task.Version)
 	if err != nil {
 		return 0, err
 	}
@@ -134,12 +203,62 @@ func UpdateTask(db *sql.DB, task models.Task) (int64, error) {
 	return result.RowsAffected()
 }
 
-// DeleteTask удаляет задачу по её ID.
-func DeleteTask(db *sql.DB, id int) (int64, error) {
-	result, err := db.Exec("DELETE FROM scheduler WHERE id = ?", id)
+// restoreTask записывает задачу с сохранением её ID в рамках транзакции
+// и возвращает её с новой версией. Удалённая задача создаётся заново с версией
+// больше последней из журнала: иначе версия повторила бы ETag, уже выданный клиентам.
+func restoreTask(ctx context.Context, tx *sql.Tx, task models.Task) (*models.Task, error) {
+	title, comment, err := encryptTask(task)
+	if err != nil {
+		return nil, err
+	}
+	version, err := lastAuditVersion(ctx, tx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO scheduler (id, date, title, comment, repeat, project_id, priority, created_at, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			date = excluded.date, title = excluded.title, comment = excluded.comment,
+			repeat = excluded.repeat, project_id = excluded.project_id, priority = excluded.priority,
+			version = scheduler.version + 1
+		RETURNING version
+	`
+	err = tx.QueryRowContext(ctx, query, task.ID, task.Date, title, comment, task.Repeat,
+		nullableID(task.ProjectID), task.Priority, task.CreatedAt, version+1).Scan(&task.Version)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// deleteTask удаляет задачу через переданное подключение или транзакцию.
+// Если version не равна 0, задача удаляется только при совпадении версии.
+func deleteTask(ctx context.Context, ex execer, id int, version int) (int64, error) {
+	result, err := ex.ExecContext(ctx,
+		"DELETE FROM scheduler WHERE id = ? AND (? = 0 OR version = ?)",
+		id, version, version,
+	)
 	if err != nil {
 		return 0, err
 	}
 
 	return result.RowsAffected()
 }
+
+// nullableID превращает пустой строковый идентификатор в NULL.
+func nullableID(id string) any {
+	if id == "" {
+		return nil
+	}
+	return id
+}
+
+// formatNullID возвращает идентификатор строкой или пустую строку для NULL.
+func formatNullID(id sql.NullInt64) string {
+	if !id.Valid {
+		return ""
+	}
+	return strconv.FormatInt(id.Int64, 10)
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"strconv"
+
+	"go_final_project/models"
+)
+
+// ImportTasks добавляет задачи одной транзакцией и возвращает их новые ID.
+// При replace все существующие задачи предварительно удаляются, а ID
+// импортируемых задач сохраняются; иначе задачи получают новые ID.
+// Удаление и добавление задач записываются в журнал в той же транзакции
+// по образцу записи audit. Возвращает также пути к файлам вложений удалённых
+// задач: их нужно удалить после успешного импорта.
+// projects задаёт по индексу задачи название проекта для задач без ProjectID
+// (nil - без проектов): проект ищется в той же транзакции (см. projectIDByName).
+func ImportTasks(ctx context.Context, db *DB, tasks []models.Task, projects []string, replace bool, audit models.AuditRecord) ([]int64, []string, error) {
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var paths []string
+	if replace {
+		removed := audit
+		removed.Action = models.AuditDelete
+		paths, err = auditRemovedTasks(ctx, tx, TaskFilter{IncludeArchived: true, Limit: -1}, removed)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM scheduler"); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	projectIDs := make(map[string]string)
+	ids := make([]int64, 0, len(tasks))
+	for i, task := range tasks {
+		if task.ProjectID == "" && i < len(projects) && projects[i] != "" {
+			if task.ProjectID, err = projectIDByName(ctx, tx, projectIDs, projects[i]); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		var id any
+		if replace && task.ID != "" {
+			id = task.ID
+		}
+
+		title, comment, err := encryptTask(task)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO scheduler (id, date, title, comment, repeat, project_id, priority, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, task.Date, title, comment, task.Repeat,
+			nullableID(task.ProjectID), task.Priority, task.CreatedAt,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		taskID, err := res.LastInsertId()
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := setTaskTags(ctx, tx, taskID, task.Tags); err != nil {
+			return nil, nil, err
+		}
+
+		created := audit
+		created.Action = models.AuditCreate
+		created.TaskID = strconv.FormatInt(taskID, 10)
+		task.ID = created.TaskID
+		task.Version = 1
+		if created.After, err = models.MarshalTaskSnapshot(task); err != nil {
+			return nil, nil, err
+		}
+		if _, err := addAuditRecord(ctx, tx, created); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, taskID)
+	}
+
+	return ids, paths, tx.Commit()
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migrations содержит изменения схемы, применяемые по порядку.
+// Номер миграции хранится в PRAGMA user_version, поэтому новые
+// изменения добавляются только в конец списка.
+var migrations = []string{
+	// 1: журнал изменений задач
+	`
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		actor TEXT NOT NULL DEFAULT '',
+		before TEXT,
+		after TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_task ON audit_log(task_id);
+	`,
+	// 2: теги задач (многие ко многим)
+	`
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE
+	);
+	CREATE TABLE IF NOT EXISTS task_tags (
+		task_id INTEGER NOT NULL,
+		tag_id INTEGER NOT NULL,
+		PRIMARY KEY (task_id, tag_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags(tag_id);
+	CREATE TRIGGER IF NOT EXISTS scheduler_delete_tags AFTER DELETE ON scheduler
+	BEGIN
+		DELETE FROM task_tags WHERE task_id = old.id;
+	END;
+	`,
+	// 3: проекты (списки задач)
+	`
+	CREATE TABLE IF NOT EXISTS projects (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		color TEXT NOT NULL DEFAULT '',
+		archived INTEGER NOT NULL DEFAULT 0
+	);
+	ALTER TABLE scheduler ADD COLUMN project_id INTEGER REFERENCES projects(id);
+	CREATE INDEX IF NOT EXISTS idx_project ON scheduler(project_id);
+	`,
+	// 4: подзадачи (пункты чек-листа)
+	`
+	CREATE TABLE IF NOT EXISTS subtasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		done INTEGER NOT NULL DEFAULT 0,
+		position INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE INDEX IF NOT EXISTS idx_subtasks_task ON subtasks(task_id, position);
+	CREATE TRIGGER IF NOT EXISTS scheduler_delete_subtasks AFTER DELETE ON scheduler
+	BEGIN
+		DELETE FROM subtasks WHERE task_id = old.id;
+	END;
+	`,
+	// 5: приоритет и время создания задачи
+	`
+	ALTER TABLE scheduler ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE scheduler ADD COLUMN created_at TEXT NOT NULL DEFAULT '';
+	`,
+	// 6: вложения задач (файл на диске или BLOB)
+	`
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		filename TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		path TEXT NOT NULL DEFAULT '',
+		data BLOB,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_attachments_task ON attachments(task_id);
+	CREATE TRIGGER IF NOT EXISTS scheduler_delete_attachments AFTER DELETE ON scheduler
+	BEGIN
+		DELETE FROM attachments WHERE task_id = old.id;
+	END;
+	`,
+	// 7: полнотекстовый поиск по заголовку и комментарию
+	`
+	CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(
+		title, comment, content='scheduler', content_rowid='id'
+	);
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_insert AFTER INSERT ON scheduler
+	BEGIN
+		INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+	END;
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_delete AFTER DELETE ON scheduler
+	BEGIN
+		INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment)
+		VALUES ('delete', old.id, old.title, old.comment);
+	END;
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler
+	BEGIN
+		INSERT INTO scheduler_fts (scheduler_fts, rowid, title, comment)
+		VALUES ('delete', old.id, old.title, old.comment);
+		INSERT INTO scheduler_fts (rowid, title, comment) VALUES (new.id, new.title, new.comment);
+	END;
+	INSERT INTO scheduler_fts (scheduler_fts) VALUES ('rebuild');
+	`,
+	// 8: поиск без учёта регистра и различий ё/е.
+	// Токенизатор unicode61 сам приводит к нижнему регистру любые алфавиты,
+	// а ё заменяется на е при индексации, поэтому индекс хранит нормализованную
+	// копию текста, а не ссылается на таблицу scheduler.
+	`
+	DROP TRIGGER IF EXISTS scheduler_fts_insert;
+	DROP TRIGGER IF EXISTS scheduler_fts_delete;
+	DROP TRIGGER IF EXISTS scheduler_fts_update;
+	DROP TABLE IF EXISTS scheduler_fts;
+	CREATE VIRTUAL TABLE scheduler_fts USING fts5(
+		title, comment, tokenize = 'unicode61 remove_diacritics 2'
+	);
+	CREATE TRIGGER scheduler_fts_insert AFTER INSERT ON scheduler
+	BEGIN
+		INSERT INTO scheduler_fts (rowid, title, comment) VALUES (
+			new.id,
+			replace(replace(new.title, 'ё', 'е'), 'Ё', 'Е'),
+			replace(replace(new.comment, 'ё', 'е'), 'Ё', 'Е')
+		);
+	END;
+	CREATE TRIGGER scheduler_fts_delete AFTER DELETE ON scheduler
+	BEGIN
+		DELETE FROM scheduler_fts WHERE rowid = old.id;
+	END;
+	CREATE TRIGGER scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler
+	BEGIN
+		DELETE FROM scheduler_fts WHERE rowid = old.id;
+		INSERT INTO scheduler_fts (rowid, title, comment) VALUES (
+			new.id,
+			replace(replace(new.title, 'ё', 'е'), 'Ё', 'Е'),
+			replace(replace(new.comment, 'ё', 'е'), 'Ё', 'Е')
+		);
+	END;
+	INSERT INTO scheduler_fts (rowid, title, comment)
+	SELECT id,
+		replace(replace(title, 'ё', 'е'), 'Ё', 'Е'),
+		replace(replace(comment, 'ё', 'е'), 'Ё', 'Е')
+	FROM scheduler;
+	`,
+	// 9: версия задачи для оптимистичной блокировки
+	`
+	ALTER TABLE scheduler ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+	`,
+	// 10: зашифрованные значения (enc:v1:...) не попадают в полнотекстовый индекс,
+	// иначе индекс хранил бы бессмысленные фрагменты шифротекста
+	`
+	DROP TRIGGER IF EXISTS scheduler_fts_insert;
+	DROP TRIGGER IF EXISTS scheduler_fts_update;
+	CREATE TRIGGER scheduler_fts_insert AFTER INSERT ON scheduler
+	BEGIN
+		INSERT INTO scheduler_fts (rowid, title, comment) VALUES (
+			new.id,
+			CASE WHEN substr(new.title, 1, 7) = 'enc:v1:' THEN ''
+				ELSE replace(replace(new.title, 'ё', 'е'), 'Ё', 'Е') END,
+			CASE WHEN substr(new.comment, 1, 7) = 'enc:v1:' THEN ''
+				ELSE replace(replace(new.comment, 'ё', 'е'), 'Ё', 'Е') END
+		);
+	END;
+	CREATE TRIGGER scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler
+	BEGIN
+		DELETE FROM scheduler_fts WHERE rowid = old.id;
+		INSERT INTO scheduler_fts (rowid, title, comment) VALUES (
+			new.id,
+			CASE WHEN substr(new.title, 1, 7) = 'enc:v1:' THEN ''
+				ELSE replace(replace(new.title, 'ё', 'е'), 'Ё', 'Е') END,
+			CASE WHEN substr(new.comment, 1, 7) = 'enc:v1:' THEN ''
+				ELSE replace(replace(new.comment, 'ё', 'е'), 'Ё', 'Е') END
+		);
+	END;
+	`,
+}
+
+// migrate применяет к базе данных миграции, которые ещё не были выполнены.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %v", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		log.Printf("Applying migration %d...", i+1)
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d failed: %v", i+1, err)
+		}
+		// PRAGMA не поддерживает параметры запроса
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update schema version: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
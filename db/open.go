@@ -0,0 +1,170 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config - настройки подключения к SQLite. Прагмы применяются к каждому
+// соединению пула, так как драйвер выполняет их при открытии соединения.
+type Config struct {
+	JournalMode  string        // режим журнала: WAL, DELETE, TRUNCATE, PERSIST, MEMORY, OFF
+	Synchronous  string        // уровень синхронизации: OFF, NORMAL, FULL, EXTRA
+	BusyTimeout  time.Duration // ожидание блокировки внутри SQLite
+	ForeignKeys  bool          // проверка внешних ключей
+	MaxOpenConns int           // 0 - без ограничения
+	MaxIdleConns int
+	// BusyRetries - повторы запроса, не дождавшегося блокировки за BusyTimeout
+	BusyRetries int
+	// QueryTimeout - ограничение времени одного запроса (0 - без ограничения)
+	QueryTimeout time.Duration
+}
+
+// DefaultConfig возвращает настройки по умолчанию: WAL позволяет читать во время записи,
+// а NORMAL в режиме WAL не теряет целостность базы при сбое.
+func DefaultConfig() Config {
+	return Config{
+		JournalMode:  "WAL",
+		Synchronous:  "NORMAL",
+		BusyTimeout:  time.Second,
+		ForeignKeys:  true,
+		MaxIdleConns: 2,
+		BusyRetries:  DefaultBusyRetries,
+		QueryTimeout: DefaultQueryTimeout,
+	}
+}
+
+// journalModes и synchronousLevels - допустимые значения прагм
+var (
+	journalModes      = []string{"WAL", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "OFF"}
+	synchronousLevels = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+)
+
+// LoadConfig читает настройки подключения из переменных окружения
+// TODO_DB_JOURNAL_MODE, TODO_DB_SYNCHRONOUS, TODO_DB_BUSY_TIMEOUT, TODO_DB_FOREIGN_KEYS,
+// TODO_DB_MAX_OPEN_CONNS, TODO_DB_MAX_IDLE_CONNS, TODO_DB_BUSY_RETRIES и TODO_DB_TIMEOUT.
+// Незаданные переменные оставляют значения DefaultConfig.
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	if value := os.Getenv("TODO_DB_JOURNAL_MODE"); value != "" {
+		cfg.JournalMode = strings.ToUpper(value)
+	}
+	if value := os.Getenv("TODO_DB_SYNCHRONOUS"); value != "" {
+		cfg.Synchronous = strings.ToUpper(value)
+	}
+	if value := os.Getenv("TODO_DB_FOREIGN_KEYS"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid TODO_DB_FOREIGN_KEYS: %q", value)
+		}
+		cfg.ForeignKeys = enabled
+	}
+
+	durations := []struct {
+		name string
+		dest *time.Duration
+	}{
+		{"TODO_DB_BUSY_TIMEOUT", &cfg.BusyTimeout},
+		{"TODO_DB_TIMEOUT", &cfg.QueryTimeout},
+	}
+	for _, d := range durations {
+		if value := os.Getenv(d.name); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration < 0 {
+				return cfg, fmt.Errorf("invalid %s: %q", d.name, value)
+			}
+			*d.dest = duration
+		}
+	}
+
+	numbers := []struct {
+		name string
+		dest *int
+	}{
+		{"TODO_DB_MAX_OPEN_CONNS", &cfg.MaxOpenConns},
+		{"TODO_DB_MAX_IDLE_CONNS", &cfg.MaxIdleConns},
+		{"TODO_DB_BUSY_RETRIES", &cfg.BusyRetries},
+	}
+	for _, n := range numbers {
+		if value := os.Getenv(n.name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 0 {
+				return cfg, fmt.Errorf("invalid %s: %q", n.name, value)
+			}
+			*n.dest = number
+		}
+	}
+
+	return cfg, cfg.validate()
+}
+
+// validate проверяет значения прагм, которые попадают в строку подключения
+func (cfg Config) validate() error {
+	if !contains(journalModes, cfg.JournalMode) {
+		return fmt.Errorf("unknown journal mode %q", cfg.JournalMode)
+	}
+	if !contains(synchronousLevels, cfg.Synchronous) {
+		return fmt.Errorf("unknown synchronous level %q", cfg.Synchronous)
+	}
+	return nil
+}
+
+// contains сообщает, есть ли значение в списке
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DB - подключение к базе вместе с его ограничением времени запроса
+// и числом повторов при блокировке. Функции пакета берут эти настройки
+// из переданного подключения, поэтому разные подключения не влияют друг на друга.
+// Подключение, обёрнутое без Open, выполняет запросы без повторов и без ограничения времени.
+type DB struct {
+	*sql.DB
+	busyRetries  int
+	queryTimeout time.Duration
+}
+
+// Open открывает базу данных с настройками cfg и проверяет подключение.
+func Open(path string, cfg Config) (*DB, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	foreignKeys := 0
+	if cfg.ForeignKeys {
+		foreignKeys = 1
+	}
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.BusyTimeout.Milliseconds()))
+	params.Add("_pragma", "journal_mode("+cfg.JournalMode+")")
+	params.Add("_pragma", "synchronous("+cfg.Synchronous+")")
+	params.Add("_pragma", fmt.Sprintf("foreign_keys(%d)", foreignKeys))
+	// Транзакции сразу берут блокировку на запись: иначе транзакция, начавшая с чтения,
+	// получает SQLITE_BUSY при первой записи без ожидания busy_timeout
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &DB{DB: db, busyRetries: cfg.BusyRetries, queryTimeout: cfg.QueryTimeout}, nil
+}
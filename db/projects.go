@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"go_final_project/models"
+)
+
+// Ошибки проверки проекта, в который переносится задача
+var (
+	ErrProjectUnknown  = errors.New("project does not exist")
+	ErrProjectArchived = errors.New("project is archived")
+)
+
+// AddProject добавляет новый проект и возвращает его ID.
+func AddProject(ctx context.Context, db *DB, project models.Project) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := execContext(ctx, db,
+		"INSERT INTO projects (name, color, archived) VALUES (?, ?, ?)",
+		project.Name, project.Color, project.Archived,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetProjectByID возвращает проект по его ID.
+func GetProjectByID(ctx context.Context, db *DB, id int) (*models.Project, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var project *models.Project
+	err := queryRowContext(ctx, db, func(row scanner) error {
+		var err error
+		project, err = scanProject(row)
+		return err
+	}, "SELECT id, name, color, archived FROM projects WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("project %w", ErrNotFound)
+	}
+	return project, err
+}
+
+// ListProjects возвращает проекты, упорядоченные по имени.
+// Архивные проекты включаются только при includeArchived.
+func ListProjects(ctx context.Context, db *DB, includeArchived bool) ([]models.Project, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := queryContext(ctx, db,
+		"SELECT id, name, color, archived FROM projects WHERE ? OR archived = 0 ORDER BY name, id",
+		includeArchived,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []models.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, *project)
+	}
+	return projects, rows.Err()
+}
+
+// UpdateProject обновляет данные проекта.
+func UpdateProject(ctx context.Context, db *DB, project models.Project) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := execContext(ctx, db,
+		"UPDATE projects SET name = ?, color = ?, archived = ? WHERE id = ?",
+		project.Name, project.Color, project.Archived, project.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteProject удаляет проект в одной транзакции. При cascade задачи проекта
+// удаляются вместе с ним, а их удаление записывается в журнал по образцу audit;
+// иначе задачи остаются без проекта. Возвращает число удалённых проектов
+// и пути к файлам вложений удалённых задач.
+func DeleteProject(ctx context.Context, db *DB, id int, cascade bool, audit models.AuditRecord) (int64, []string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	var paths []string
+	if cascade {
+		filter := TaskFilter{ProjectID: strconv.Itoa(id), Limit: -1}
+		if paths, err = auditRemovedTasks(ctx, tx, filter, audit); err != nil {
+			return 0, nil, err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM scheduler WHERE project_id = ?", id)
+	} else {
+		_, err = tx.ExecContext(ctx, "UPDATE scheduler SET project_id = NULL WHERE project_id = ?", id)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM projects WHERE id = ?", id)
+	if err != nil {
+		return 0, nil, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+	return rowsAffected, paths, tx.Commit()
+}
+
+// checkProject в рамках транзакции проверяет, что в проект projectID
+// (пустой - без проекта) можно перенести задачу
+func checkProject(ctx context.Context, tx *sql.Tx, projectID string) error {
+	if projectID == "" {
+		return nil
+	}
+	var archived bool
+	err := tx.QueryRowContext(ctx, "SELECT archived FROM projects WHERE id = ?", projectID).Scan(&archived)
+	if err == sql.ErrNoRows {
+		return ErrProjectUnknown
+	}
+	if err != nil {
+		return err
+	}
+	if archived {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+// projectIDByName в рамках транзакции возвращает ID проекта с названием name,
+// создавая проект при его отсутствии. Название сравнивается со всеми проектами,
+// включая архивные, без учёта регистра и считая "_" равным пробелу, так как
+// todo.txt их не различает. ids хранит найденные ID между вызовами.
+func projectIDByName(ctx context.Context, tx *sql.Tx, ids map[string]string, name string) (string, error) {
+	key := projectNameKey(name)
+	if id, ok := ids[key]; ok {
+		return id, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, name, color, archived FROM projects ORDER BY id")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return "", err
+		}
+		if projectNameKey(project.Name) == key {
+			ids[key] = project.ID
+			return project.ID, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO projects (name) VALUES (?)", name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create project %q: %w", name, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+	ids[key] = strconv.FormatInt(id, 10)
+	log.Printf("Project %q created by import", name)
+	return ids[key], nil
+}
+
+// projectNameKey приводит название проекта к виду для сравнения
+func projectNameKey(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", " ")
+}
+
+// scanProject читает проект из строки результата запроса.
+func scanProject(s scanner) (*models.Project, error) {
+	var project models.Project
+	var id int64
+	if err := s.Scan(&id, &project.Name, &project.Color, &project.Archived); err != nil {
+		return nil, err
+	}
+	project.ID = strconv.FormatInt(id, 10)
+	return &project, nil
+}
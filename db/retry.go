@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrBusy возвращается, если база осталась заблокированной после всех повторов
+var ErrBusy = errors.New("database is busy")
+
+// Параметры повторов при блокировке базы
+const (
+	DefaultBusyRetries = 5
+	minBackoff         = 10 * time.Millisecond
+	maxBackoff         = 500 * time.Millisecond
+)
+
+// isBusy сообщает, что запрос не выполнен из-за блокировки базы другим соединением
+func isBusy(err error) bool {
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	// Расширенные коды (например, SQLITE_BUSY_SNAPSHOT) содержат основной код в младшем байте
+	code := e.Code() & 0xff
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// retryBusy выполняет fn, повторяя её при блокировке базы с экспоненциальной
+// задержкой и случайным разбросом, пока не исчерпаны повторы подключения или контекст.
+func (db *DB) retryBusy(ctx context.Context, fn func() error) error {
+	delay := minBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isBusy(err) {
+			return err
+		}
+		if attempt >= db.busyRetries {
+			return fmt.Errorf("%w: %v", ErrBusy, err)
+		}
+
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+// execContext выполняет запрос с повторами при блокировке базы
+func execContext(ctx context.Context, db *DB, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := db.retryBusy(ctx, func() error {
+		var err error
+		result, err = db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// queryContext выполняет запрос на чтение с повторами при блокировке базы
+func queryContext(ctx context.Context, db *DB, query string, args ...any) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := db.retryBusy(ctx, func() error {
+		var err error
+		rows, err = db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// queryRowContext выполняет запрос, возвращающий одну строку, и читает её функцией scan
+// с повторами при блокировке базы. Ошибка *sql.Row появляется только при чтении,
+// поэтому повторяется запрос вместе с ним.
+func queryRowContext(ctx context.Context, db *DB, scan func(scanner) error, query string, args ...any) error {
+	return db.retryBusy(ctx, func() error {
+		return scan(db.QueryRowContext(ctx, query, args...))
+	})
+}
+
+// beginTx начинает транзакцию с повторами при блокировке базы.
+// Транзакции открываются сразу на запись (_txlock=immediate), поэтому после
+// успешного BEGIN запросы внутри транзакции не получают SQLITE_BUSY.
+func beginTx(ctx context.Context, db *DB) (*sql.Tx, error) {
+	var tx *sql.Tx
+	err := db.retryBusy(ctx, func() error {
+		var err error
+		tx, err = db.BeginTx(ctx, nil)
+		return err
+	})
+	return tx, err
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go_final_project/models"
+)
+
+// ErrSubtaskSetMismatch возвращается, если порядок задан не для всех подзадач задачи
+var ErrSubtaskSetMismatch = errors.New("subtask list does not match task subtasks")
+
+// AddSubtask добавляет подзадачу в конец чек-листа и возвращает её ID.
+func AddSubtask(ctx context.Context, db *DB, taskID int, title string) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := execContext(ctx, db, `
+		INSERT INTO subtasks (task_id, title, position)
+		SELECT ?, ?, COALESCE(MAX(position), 0) + 1 FROM subtasks WHERE task_id = ?`,
+		taskID, title, taskID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetSubtaskByID возвращает подзадачу по её ID.
+func GetSubtaskByID(ctx context.Context, db *DB, id int) (*models.Subtask, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	var subtask *models.Subtask
+	err := queryRowContext(ctx, db, func(row scanner) error {
+		var err error
+		subtask, err = scanSubtask(row)
+		return err
+	}, "SELECT id, task_id, title, done, position FROM subtasks WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("subtask %w", ErrNotFound)
+	}
+	return subtask, err
+}
+
+// ListSubtasks возвращает подзадачи задачи в порядке их позиций.
+func ListSubtasks(ctx context.Context, db *DB, taskID int) ([]models.Subtask, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := queryContext(ctx, db,
+		"SELECT id, task_id, title, done, position FROM subtasks WHERE task_id = ? ORDER BY position, id",
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subtasks := []models.Subtask{}
+	for rows.Next() {
+		subtask, err := scanSubtask(rows)
+		if err != nil {
+			return nil, err
+		}
+		subtasks = append(subtasks, *subtask)
+	}
+	return subtasks, rows.Err()
+}
+
+// ToggleSubtask инвертирует отметку о выполнении подзадачи.
+func ToggleSubtask(ctx context.Context, db *DB, id int) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := execContext(ctx, db, "UPDATE subtasks SET done = 1 - done WHERE id = ?", id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReorderSubtasks задаёт новый порядок подзадач.
+// Список ids должен содержать все подзадачи задачи ровно по одному разу.
+func ReorderSubtasks(ctx context.Context, db *DB, taskID int, ids []int) error {
+	// Повторяющийся ID означает, что часть подзадач не получила бы новую позицию
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return ErrSubtaskSetMismatch
+		}
+		seen[id] = true
+	}
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM subtasks WHERE task_id = ?", taskID).Scan(&count); err != nil {
+		return err
+	}
+	if count != len(ids) {
+		return ErrSubtaskSetMismatch
+	}
+
+	for i, id := range ids {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE subtasks SET position = ? WHERE id = ? AND task_id = ?",
+			i+1, id, taskID,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return ErrSubtaskSetMismatch
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteSubtask удаляет подзадачу по её ID.
+func DeleteSubtask(ctx context.Context, db *DB, id int) (int64, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := execContext(ctx, db, "DELETE FROM subtasks WHERE id = ?", id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanSubtask читает подзадачу из строки результата запроса.
+func scanSubtask(s scanner) (*models.Subtask, error) {
+	var subtask models.Subtask
+	var id, taskID int64
+	err := s.Scan(&id, &taskID, &subtask.Title, &subtask.Done, &subtask.Position)
+	if err != nil {
+		return nil, err
+	}
+	subtask.ID = strconv.FormatInt(id, 10)
+	subtask.TaskID = strconv.FormatInt(taskID, 10)
+	return &subtask, nil
+}
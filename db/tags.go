@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+)
+
+// setTaskTags заменяет набор тегов задачи в рамках переданной транзакции.
+func setTaskTags(ctx context.Context, ex execer, taskID int64, tags []string) error {
+	if _, err := ex.ExecContext(ctx, "DELETE FROM task_tags WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := ex.ExecContext(ctx, "INSERT OR IGNORE INTO tags (name) VALUES (?)", tag); err != nil {
+			return err
+		}
+		_, err := ex.ExecContext(ctx,
+			"INSERT INTO task_tags (task_id, tag_id) SELECT ?, id FROM tags WHERE name = ?",
+			taskID, tag,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTaskTags возвращает отсортированный список тегов задачи.
+func GetTaskTags(ctx context.Context, db *DB, taskID int64) ([]string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := queryContext(ctx, db, `
+		SELECT t.name FROM task_tags tt JOIN tags t ON t.id = tt.tag_id
+		WHERE tt.task_id = ? ORDER BY t.name`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// taskTagsTx возвращает теги задачи в рамках транзакции, отсортированные по имени.
+func taskTagsTx(ctx context.Context, tx *sql.Tx, taskID int64) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT t.name FROM task_tags tt JOIN tags t ON t.id = tt.tag_id
+		WHERE tt.task_id = ? ORDER BY t.name`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// splitTags разбирает результат group_concat в отсортированный список тегов.
+func splitTags(s sql.NullString) []string {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+	tags := strings.Split(s.String, ",")
+	sort.Strings(tags)
+	return tags
+}
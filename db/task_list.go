@@ -0,0 +1,441 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go_final_project/models"
+)
+
+// Режимы фильтрации по тегам
+const (
+	TagModeAny = "or"  // задача содержит хотя бы один из тегов
+	TagModeAll = "and" // задача содержит все теги
+)
+
+// ProjectNone - значение фильтра для задач без проекта
+const ProjectNone = "none"
+
+// Значения фильтра по повторению, кроме типов правил
+const (
+	RepeatNone = "none" // одноразовые задачи
+	RepeatAny  = "any"  // повторяющиеся задачи с любым правилом
+)
+
+// RepeatTypes - типы правил повторения, по которым можно фильтровать список
+var RepeatTypes = []string{"d", "y"}
+
+// sortColumns - разрешённые ключи сортировки и соответствующие им выражения SQL.
+// В запрос попадают только значения из этой таблицы, а не пользовательский ввод.
+var sortColumns = map[string]string{
+	"date":     "scheduler.date",
+	"priority": "scheduler.priority",
+	"title":    "scheduler.title",
+	"created":  "scheduler.created_at",
+}
+
+// SortField - ключ сортировки списка задач
+type SortField struct {
+	Key  string
+	Desc bool
+}
+
+// DefaultSort - сортировка списка задач по умолчанию
+var DefaultSort = []SortField{{Key: "date"}}
+
+// ParseTaskSort разбирает параметр сортировки вида "-priority,date".
+// Минус перед ключом означает сортировку по убыванию.
+func ParseTaskSort(s string) ([]SortField, error) {
+	if s == "" {
+		return DefaultSort, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		field := SortField{Key: strings.TrimSpace(part)}
+		if strings.HasPrefix(field.Key, "-") {
+			field.Desc = true
+			field.Key = field.Key[1:]
+		}
+		if _, ok := sortColumns[field.Key]; !ok {
+			return nil, fmt.Errorf("unknown sort key: %q", field.Key)
+		}
+		if seen[field.Key] {
+			return nil, fmt.Errorf("duplicate sort key: %q", field.Key)
+		}
+		seen[field.Key] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// orderBy строит выражение ORDER BY по разрешённым ключам.
+// Без явной сортировки результаты поиска упорядочиваются по релевантности.
+// ID в конце делает порядок однозначным для одинаковых значений.
+func orderBy(fields []SortField, ranked bool) string {
+	var parts []string
+	if len(fields) == 0 {
+		if ranked {
+			parts = append(parts, "scheduler_fts.rank")
+		}
+		fields = DefaultSort
+	}
+
+	for _, field := range fields {
+		expr := sortColumns[field.Key]
+		if field.Desc {
+			expr += " DESC"
+		}
+		parts = append(parts, expr)
+	}
+	parts = append(parts, "scheduler.id")
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// TaskFilter задаёт условия выборки списка задач
+type TaskFilter struct {
+	Tags    []string
+	TagMode string
+	// ProjectID ограничивает выборку одним проектом (или задачами без проекта).
+	// Без него задачи архивных проектов скрываются, если не задан IncludeArchived.
+	ProjectID       string
+	IncludeArchived bool
+	// Search - полнотекстовый поиск по заголовку и комментарию
+	Search string
+	// Date ограничивает выборку одной датой (YYYYMMDD)
+	Date string
+	// From и To ограничивают диапазон дат включительно, Before - строго раньше даты
+	// (просроченные задачи). Все три условия используют индекс idx_date.
+	From   string
+	To     string
+	Before string
+	// Repeat отбирает задачи по типу повторения: RepeatNone, RepeatAny или тип правила (d, y)
+	Repeat string
+	// HasComment отбирает задачи с комментарием (true) или без него (false)
+	HasComment *bool
+	Sort       []SortField
+	Limit      int
+	// After - курсор: выборка продолжается после этой задачи (только при сортировке по дате)
+	After *Cursor
+}
+
+// ErrCursorSort возвращается, если курсор передан вместе с сортировкой не по дате
+var ErrCursorSort = errors.New("cursor pagination requires ordering by date")
+
+// Cursor - позиция в списке задач, упорядоченном по дате и ID.
+// Страница после курсора выбирается условием (date, id) > (Date, ID) по индексу idx_date,
+// поэтому задачи, добавленные во время листания, не сдвигают следующие страницы.
+type Cursor struct {
+	Date string
+	ID   int64
+}
+
+// String кодирует курсор в непрозрачную строку для клиента
+func (c Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Date + ":" + strconv.FormatInt(c.ID, 10)))
+}
+
+// ParseCursor разбирает строку, полученную из Cursor.String
+func ParseCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor: %v", err)
+	}
+	date, id, ok := strings.Cut(string(data), ":")
+	if !ok || len(date) != 8 {
+		return Cursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	if _, err := strconv.Atoi(date); err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	cursor := Cursor{Date: date}
+	if cursor.ID, err = strconv.ParseInt(id, 10, 64); err != nil || cursor.ID <= 0 {
+		return Cursor{}, fmt.Errorf("invalid cursor %q", s)
+	}
+	return cursor, nil
+}
+
+// keysetOrder сообщает, что список упорядочен по дате и ID и его можно листать курсором
+func keysetOrder(filter TaskFilter) bool {
+	if len(filter.Sort) == 0 {
+		return filter.Search == ""
+	}
+	return len(filter.Sort) == 1 && filter.Sort[0] == SortField{Key: "date"}
+}
+
+// ListTasksPage возвращает страницу задач и курсор следующей страницы.
+// Курсор равен nil, если страница последняя или порядок не допускает листания курсором.
+func ListTasksPage(ctx context.Context, db *DB, filter TaskFilter) ([]models.Task, *Cursor, error) {
+	paged := keysetOrder(filter) && filter.Limit > 0
+	if paged {
+		// Лишняя задача показывает, есть ли следующая страница
+		filter.Limit++
+	}
+
+	tasks, err := ListTasks(ctx, db, filter)
+	if err != nil || !paged || len(tasks) < filter.Limit {
+		return tasks, nil, err
+	}
+
+	tasks = tasks[:len(tasks)-1]
+	last := tasks[len(tasks)-1]
+	id, err := strconv.ParseInt(last.ID, 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tasks, &Cursor{Date: last.Date, ID: id}, nil
+}
+
+// ExplainTasks возвращает план запроса SQLite для выборки по фильтру (EXPLAIN QUERY PLAN).
+// Нужен для проверки того, что листание и фильтры используют индексы.
+func ExplainTasks(ctx context.Context, db *DB, filter TaskFilter) ([]string, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	query, args, err := taskQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := queryContext(ctx, db, "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			return nil, err
+		}
+		plan = append(plan, detail)
+	}
+	return plan, rows.Err()
+}
+
+// ListTasks возвращает задачи, подходящие под фильтр, в заданном порядке (по умолчанию по дате).
+func ListTasks(ctx context.Context, db *DB, filter TaskFilter) ([]models.Task, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	tasks := []models.Task{}
+	err := ForEachTask(ctx, db, filter, func(task models.Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// ForEachTask вызывает fn для каждой задачи, подходящей под фильтр, не загружая
+// весь список в память. Ошибка fn прерывает обход и возвращается вызывающему.
+func ForEachTask(ctx context.Context, db *DB, filter TaskFilter, fn func(models.Task) error) error {
+	query, args, err := taskQuery(filter)
+	if err != nil {
+		return err
+	}
+
+	rows, err := queryContext(ctx, db, query, args...)
+	if err != nil {
+		return err
+	}
+	return scanTasks(rows, fn)
+}
+
+// listTasksTx возвращает задачи, подходящие под фильтр, в рамках транзакции
+func listTasksTx(ctx context.Context, tx *sql.Tx, filter TaskFilter) ([]models.Task, error) {
+	query, args, err := taskQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	tasks := []models.Task{}
+	err = scanTasks(rows, func(task models.Task) error {
+		tasks = append(tasks, task)
+		return nil
+	})
+	return tasks, err
+}
+
+// scanTasks читает задачи из результата запроса taskQuery, вызывает fn для каждой
+// и закрывает rows
+func scanTasks(rows *sql.Rows, fn func(models.Task) error) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		var task models.Task
+		var id int64 // SQLite возвращает id в виде INTEGER
+		var projectID sql.NullInt64
+		var tags sql.NullString
+		var checklist models.Checklist
+		err := rows.Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat, &projectID,
+			&task.Priority, &task.CreatedAt, &task.Version, &tags, &checklist.Total, &checklist.Done)
+		if err != nil {
+			return err
+		}
+		task.ID = strconv.FormatInt(id, 10)
+		decryptTask(&task)
+		task.ProjectID = formatNullID(projectID)
+		task.Tags = splitTags(tags)
+		if checklist.Total > 0 {
+			task.Checklist = &checklist
+		}
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// taskQuery строит запрос списка задач по фильтру
+func taskQuery(filter TaskFilter) (string, []any, error) {
+	from, where, args := taskConditions(filter)
+
+	if filter.After != nil {
+		if !keysetOrder(filter) {
+			return "", nil, ErrCursorSort
+		}
+		where = append(where, "(scheduler.date, scheduler.id) > (?, ?)")
+		args = append(args, filter.After.Date, filter.After.ID)
+	}
+
+	query := `
+		SELECT scheduler.id, scheduler.date, scheduler.title, scheduler.comment, scheduler.repeat,
+			scheduler.project_id, scheduler.priority, scheduler.created_at, scheduler.version,
+			(SELECT group_concat(t.name) FROM task_tags tt JOIN tags t ON t.id = tt.tag_id
+			 WHERE tt.task_id = scheduler.id) AS tags,
+			(SELECT COUNT(*) FROM subtasks s WHERE s.task_id = scheduler.id) AS subtasks_total,
+			(SELECT COALESCE(SUM(s.done), 0) FROM subtasks s WHERE s.task_id = scheduler.id) AS subtasks_done
+		FROM ` + from
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += orderBy(filter.Sort, filter.Search != "") + " LIMIT ?"
+	args = append(args, filter.Limit)
+	return query, args, nil
+}
+
+// CountTasks возвращает число задач, подходящих под фильтр, без учёта курсора и лимита
+func CountTasks(ctx context.Context, db *DB, filter TaskFilter) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	from, where, args := taskConditions(filter)
+	query := "SELECT COUNT(*) FROM " + from
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	err := queryRowContext(ctx, db, func(row scanner) error {
+		return row.Scan(&total)
+	}, query, args...)
+	return total, err
+}
+
+// taskConditions возвращает источник и условия выборки задач по фильтру.
+// Значения фильтра передаются только через параметры запроса.
+func taskConditions(filter TaskFilter) (string, []string, []any) {
+	var where []string
+	var args []any
+
+	if len(filter.Tags) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(filter.Tags)), ",")
+		cond := `scheduler.id IN (
+			SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id
+			WHERE t.name IN (` + placeholders + `)`
+		for _, tag := range filter.Tags {
+			args = append(args, tag)
+		}
+		if filter.TagMode == TagModeAll {
+			cond += " GROUP BY tt.task_id HAVING COUNT(DISTINCT t.id) = ?"
+			args = append(args, len(filter.Tags))
+		}
+		where = append(where, cond+")")
+	}
+
+	switch {
+	case filter.ProjectID == ProjectNone:
+		where = append(where, "scheduler.project_id IS NULL")
+	case filter.ProjectID != "":
+		where = append(where, "scheduler.project_id = ?")
+		args = append(args, filter.ProjectID)
+	case !filter.IncludeArchived:
+		where = append(where, `(scheduler.project_id IS NULL
+			OR scheduler.project_id NOT IN (SELECT id FROM projects WHERE archived = 1))`)
+	}
+
+	if filter.Date != "" {
+		where = append(where, "scheduler.date = ?")
+		args = append(args, filter.Date)
+	}
+	if filter.From != "" {
+		where = append(where, "scheduler.date >= ?")
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where = append(where, "scheduler.date <= ?")
+		args = append(args, filter.To)
+	}
+	if filter.Before != "" {
+		where = append(where, "scheduler.date < ?")
+		args = append(args, filter.Before)
+	}
+
+	switch filter.Repeat {
+	case "":
+	case RepeatNone:
+		where = append(where, "scheduler.repeat = ''")
+	case RepeatAny:
+		where = append(where, "scheduler.repeat != ''")
+	default:
+		// Правило состоит из типа и, возможно, параметров через пробел: "y", "d 7"
+		where = append(where, "(scheduler.repeat = ? OR scheduler.repeat LIKE ?)")
+		args = append(args, filter.Repeat, filter.Repeat+" %")
+	}
+
+	if filter.HasComment != nil {
+		if *filter.HasComment {
+			where = append(where, "scheduler.comment != ''")
+		} else {
+			where = append(where, "scheduler.comment = ''")
+		}
+	}
+
+	from := "scheduler"
+	if filter.Search != "" {
+		from += " JOIN scheduler_fts ON scheduler_fts.rowid = scheduler.id"
+		where = append(where, "scheduler_fts MATCH ?")
+		args = append(args, ftsQuery(filter.Search))
+	}
+	return from, where, args
+}
+
+// yoReplacer заменяет ё на е так же, как это делают триггеры индекса scheduler_fts
+var yoReplacer = strings.NewReplacer("ё", "е", "Ё", "Е")
+
+// ftsQuery превращает пользовательскую строку в запрос FTS5.
+// Каждое слово берётся в кавычки (чтобы синтаксис FTS5 не интерпретировался)
+// и ищется как префикс; все слова должны присутствовать в задаче.
+// Регистр учитывать не нужно: его нормализует токенизатор.
+func ftsQuery(search string) string {
+	words := strings.Fields(yoReplacer.Replace(search))
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultQueryTimeout - ограничение времени одного запроса к базе по умолчанию
+const DefaultQueryTimeout = 5 * time.Second
+
+// SetQueryTimeout задаёт ограничение времени для каждого запроса через db (0 - без ограничения).
+// Вызывается до обработки запросов.
+func (db *DB) SetQueryTimeout(d time.Duration) {
+	db.queryTimeout = d
+}
+
+// withTimeout ограничивает контекст запроса временем queryTimeout подключения.
+// Массовые операции (ForEachTask, ImportTasks, Backup, Reencrypt) его не используют
+// и ограничены только контекстом вызывающего, так как их длительность зависит от объёма данных.
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if db.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, db.queryTimeout)
+}
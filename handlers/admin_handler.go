@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_final_project/db"
+)
+
+// checkAdmin проверяет токен администратора из заголовка Authorization: Bearer <токен>.
+// Если токен не настроен (TODO_ADMIN_TOKEN), административные запросы запрещены.
+func (h *Handler) checkAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if h.AdminToken == "" {
+		writeError(w, r, errAdminDisabled)
+		return false
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+		writeError(w, r, errAdminUnauthorized)
+		return false
+	}
+	return true
+}
+
+// HandleReencrypt перешифровывает поля задач по текущим настройкам шифрования,
+// например, после смены ключа (прежний ключ должен быть в TODO_ENCRYPTION_OLD_KEYS)
+func (h *Handler) HandleReencrypt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	if !h.checkAdmin(w, r) {
+		return
+	}
+
+	n, err := db.Reencrypt(r.Context(), h.DB)
+	if err != nil {
+		writeDBError(w, r, err, errReencryptFailed)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]int{"updated": n}); err != nil {
+		log.Printf("[ERROR] reencrypt: %v", err)
+	}
+}
+
+// HandleBackup отдаёт согласованный снимок базы данных в виде файла
+func (h *Handler) HandleBackup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	if !h.checkAdmin(w, r) {
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "scheduler-backup-")
+	if err != nil {
+		writeDBError(w, r, err, errBackupFailed)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "scheduler.db")
+	if err := db.Backup(r.Context(), h.DB, path); err != nil {
+		writeDBError(w, r, err, errBackupFailed)
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		writeDBError(w, r, err, errBackupReadFailed)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		writeDBError(w, r, err, errBackupReadFailed)
+		return
+	}
+
+	filename := "scheduler-" + time.Now().Format("20060102-150405") + ".db"
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Failed to send backup: %v", err)
+	}
+}
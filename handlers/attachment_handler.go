@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go_final_project/db"
+	"go_final_project/models"
+)
+
+// Способы хранения вложений
+const (
+	AttachmentStorageDisk = "disk" // файлы в каталоге на диске
+	AttachmentStorageDB   = "db"   // BLOB в таблице attachments
+)
+
+// AttachmentConfig - настройки хранения вложений
+type AttachmentConfig struct {
+	Storage string
+	Dir     string
+	MaxSize int64
+}
+
+// AttachmentListResponse структура ответа со списком вложений
+type AttachmentListResponse struct {
+	Attachments []models.Attachment `json:"attachments"`
+}
+
+// DefaultAttachmentConfig возвращает настройки вложений по умолчанию:
+// файлы до 10 МБ в каталоге ./attachments
+func DefaultAttachmentConfig() AttachmentConfig {
+	return AttachmentConfig{
+		Storage: AttachmentStorageDisk,
+		Dir:     "attachments",
+		MaxSize: 10 << 20,
+	}
+}
+
+// LoadAttachmentConfig читает настройки вложений из переменных окружения
+// TODO_ATTACH_STORAGE (disk или db), TODO_ATTACH_DIR и TODO_ATTACH_MAX_SIZE (в байтах).
+func LoadAttachmentConfig() (AttachmentConfig, error) {
+	cfg := DefaultAttachmentConfig()
+
+	if storage := os.Getenv("TODO_ATTACH_STORAGE"); storage != "" {
+		if storage != AttachmentStorageDisk && storage != AttachmentStorageDB {
+			return cfg, fmt.Errorf("invalid TODO_ATTACH_STORAGE: %s", storage)
+		}
+		cfg.Storage = storage
+	}
+	if dir := os.Getenv("TODO_ATTACH_DIR"); dir != "" {
+		cfg.Dir = dir
+	}
+	if size := os.Getenv("TODO_ATTACH_MAX_SIZE"); size != "" {
+		maxSize, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || maxSize <= 0 {
+			return cfg, fmt.Errorf("invalid TODO_ATTACH_MAX_SIZE: %s", size)
+		}
+		cfg.MaxSize = maxSize
+	}
+	return cfg, nil
+}
+
+// HandleAttachment обрабатывает запросы API для вложений
+func (h *Handler) HandleAttachment(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.uploadAttachment(w, r)
+	case http.MethodGet:
+		h.downloadAttachment(w, r)
+	case http.MethodDelete:
+		h.deleteAttachment(w, r)
+	default:
+		writeMethodNotAllowed(w, r, http.MethodPost, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// HandleAttachmentList возвращает список вложений задачи
+func (h *Handler) HandleAttachmentList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	taskID, err := strconv.Atoi(r.URL.Query().Get("task_id"))
+	if err != nil {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+
+	attachments, err := db.ListAttachments(r.Context(), h.DB, taskID)
+	if err != nil {
+		writeDBError(w, r, err, errAttachmentListFailed)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(AttachmentListResponse{Attachments: attachments}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// uploadAttachment принимает файл из поля file формы multipart/form-data.
+// Тип содержимого определяется по самому файлу, а не по заголовкам клиента.
+func (h *Handler) uploadAttachment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	taskID, err := strconv.Atoi(r.URL.Query().Get("task_id"))
+	if err != nil {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+
+	if _, err := db.GetTaskByID(r.Context(), h.DB, taskID); err != nil {
+		writeDBError(w, r, err, errTaskNotFound)
+		return
+	}
+
+	// Запас в 1 МБ на служебные части multipart-запроса
+	r.Body = http.MaxBytesReader(w, r.Body, h.Attachments.MaxSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, r, errAttachmentFileRequired.with(h.Attachments.MaxSize))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, h.Attachments.MaxSize+1))
+	if err != nil {
+		writeError(w, r, errAttachmentFileRead)
+		return
+	}
+	if int64(len(data)) > h.Attachments.MaxSize {
+		writeError(w, r, errAttachmentTooLarge)
+		return
+	}
+
+	attachment := models.Attachment{
+		TaskID:      strconv.Itoa(taskID),
+		Filename:    filepath.Base(header.Filename),
+		ContentType: http.DetectContentType(data),
+		Size:        int64(len(data)),
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var blob []byte
+	if h.Attachments.Storage == AttachmentStorageDB {
+		blob = data
+	} else {
+		attachment.Path, err = h.saveAttachmentFile(data)
+		if err != nil {
+			log.Printf("Failed to save attachment: %v", err)
+			writeError(w, r, errAttachmentSaveFailed)
+			return
+		}
+	}
+
+	id, err := db.AddAttachment(r.Context(), h.DB, attachment, blob)
+	if err != nil {
+		removeAttachmentFiles([]string{attachment.Path})
+		writeDBError(w, r, err, errAttachmentCreateFailed)
+		return
+	}
+
+	response := map[string]any{"id": strconv.FormatInt(id, 10)}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// downloadAttachment отдаёт содержимое вложения
+func (h *Handler) downloadAttachment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		writeError(w, r, errAttachmentIDInvalid)
+		return
+	}
+
+	attachment, err := db.GetAttachmentByID(r.Context(), h.DB, id)
+	if err != nil {
+		writeDBError(w, r, err, errAttachmentNotFound)
+		return
+	}
+
+	var data []byte
+	if attachment.Path != "" {
+		data, err = os.ReadFile(attachment.Path)
+	} else {
+		data, err = db.GetAttachmentData(r.Context(), h.DB, id)
+	}
+	if err != nil {
+		writeDBError(w, r, err, errAttachmentReadFailed)
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition",
+		mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Write(data)
+}
+
+// deleteAttachment удаляет вложение и его файл
+func (h *Handler) deleteAttachment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		writeError(w, r, errAttachmentIDInvalid)
+		return
+	}
+
+	attachment, err := db.GetAttachmentByID(r.Context(), h.DB, id)
+	if err != nil {
+		writeDBError(w, r, err, errAttachmentNotFound)
+		return
+	}
+
+	if _, err := db.DeleteAttachment(r.Context(), h.DB, id); err != nil {
+		writeDBError(w, r, err, errAttachmentDeleteFailed)
+		return
+	}
+	removeAttachmentFiles([]string{attachment.Path})
+
+	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// saveAttachmentFile записывает файл вложения под случайным именем и возвращает путь к нему
+func (h *Handler) saveAttachmentFile(data []byte) (string, error) {
+	if err := os.MkdirAll(h.Attachments.Dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(h.Attachments.Dir, hex.EncodeToString(name))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// attachmentPaths возвращает файлы вложений задачи для удаления вместе с ней.
+// removeAttachmentFiles удаляет файлы вложений с диска
+func removeAttachmentFiles(paths []string) {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("[ERROR] failed to remove attachment file %s: %v", path, err)
+		}
+	}
+}
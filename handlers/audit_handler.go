@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_final_project/db"
+	"go_final_project/models"
+)
+
+// DefaultAuditLimit - количество записей журнала по умолчанию
+const DefaultAuditLimit = 100
+
+// AuditListResponse структура ответа с записями журнала
+type AuditListResponse struct {
+	Records []models.AuditRecord `json:"records"`
+}
+
+// newAuditRecord возвращает запись журнала со снимками задачи до и после изменения
+func newAuditRecord(actor, action, taskID string, before, after *models.Task) (models.AuditRecord, error) {
+	rec := models.AuditRecord{
+		TaskID:    taskID,
+		Action:    action,
+		Actor:     actor,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var err error
+	if before != nil {
+		if rec.Before, err = models.MarshalTaskSnapshot(*before); err != nil {
+			return rec, err
+		}
+	}
+	if after != nil {
+		if rec.After, err = models.MarshalTaskSnapshot(*after); err != nil {
+			return rec, err
+		}
+	}
+	return rec, nil
+}
+
+// actorFromRequest определяет автора изменения.
+// Используется заголовок X-User, а при его отсутствии - адрес клиента.
+func actorFromRequest(r *http.Request) string {
+	if user := r.Header.Get("X-User"); user != "" {
+		return user
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// HandleTaskHistory возвращает журнал изменений одной задачи
+func (h *Handler) HandleTaskHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, r, errTaskIDRequired)
+		return
+	}
+
+	taskID, err := strconv.Atoi(id)
+	if err != nil || taskID <= 0 {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+
+	h.writeAuditRecords(w, r, taskID)
+}
+
+// HandleHistory возвращает журнал изменений по всем задачам
+func (h *Handler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	h.writeAuditRecords(w, r, 0)
+}
+
+// writeAuditRecords отправляет записи журнала с учётом параметра limit
+func (h *Handler) writeAuditRecords(w http.ResponseWriter, r *http.Request, taskID int) {
+	limit := DefaultAuditLimit
+	if queryLimit := r.URL.Query().Get("limit"); queryLimit != "" {
+		if parsedLimit, err := strconv.Atoi(queryLimit); err == nil && parsedLimit > 0 {
+			limit = parsedLimit
+		}
+	}
+
+	records, err := db.GetAuditRecords(r.Context(), h.DB, taskID, limit)
+	if err != nil {
+		writeDBError(w, r, err, errHistoryFailed)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(AuditListResponse{Records: records}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// HandleTaskRevert возвращает задачу в состояние, сохранённое в записи журнала.
+// Восстанавливается состояние задачи после указанного изменения, поэтому
+// для возврата удалённой задачи нужно указать предшествующую удалению запись.
+func (h *Handler) HandleTaskRevert(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	auditID := r.URL.Query().Get("audit_id")
+	if id == "" || auditID == "" {
+		writeError(w, r, errAuditIDRequired)
+		return
+	}
+
+	taskID, err := strconv.Atoi(id)
+	if err != nil {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+	recID, err := strconv.Atoi(auditID)
+	if err != nil {
+		writeError(w, r, errAuditIDInvalid)
+		return
+	}
+
+	rec, err := db.GetAuditRecordByID(r.Context(), h.DB, recID)
+	if err != nil {
+		writeDBError(w, r, err, errAuditNotFound)
+		return
+	}
+	if rec.TaskID != id {
+		writeError(w, r, errAuditOtherTask)
+		return
+	}
+	if rec.After == nil {
+		writeError(w, r, errAuditNoState)
+		return
+	}
+
+	var state models.Task
+	if err := json.Unmarshal(rec.After, &state); err != nil {
+		writeError(w, r, errAuditCorrupt)
+		return
+	}
+	state.ID = id
+
+	// Проект мог быть удалён после записи журнала
+	if state.ProjectID != "" {
+		projectID, err := strconv.Atoi(state.ProjectID)
+		if err != nil {
+			state.ProjectID = ""
+		} else if _, err := db.GetProjectByID(r.Context(), h.DB, projectID); err != nil {
+			state.ProjectID = ""
+		}
+	}
+
+	// Текущее состояние нужно для журнала; задача может быть уже удалена
+	before, err := db.GetTaskByID(r.Context(), h.DB, taskID)
+	if err != nil {
+		before = nil
+	}
+
+	// Задача, теги и запись журнала сохраняются в одной транзакции
+	op := db.BatchOp{Action: db.BatchRestore, Task: state, SetTags: true}
+	result, err := h.execTaskOp(r, op, models.AuditRevert, before, nil)
+	if err != nil {
+		writeDBError(w, r, err, errRestoreFailed)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(result.Task.Version))
+	if err := json.NewEncoder(w).Encode(result.Task); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
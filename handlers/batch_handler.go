@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_final_project/db"
+	"go_final_project/models"
+	"go_final_project/utils"
+)
+
+// maxBatchSize - максимальное число операций в одном пакетном запросе
+const maxBatchSize = 1000
+
+// Режимы пакетного запроса
+const (
+	batchAtomic     = "atomic"      // при ошибке любой операции не выполняется ни одна
+	batchBestEffort = "best_effort" // выполняются все операции, кроме ошибочных
+)
+
+// Результаты отдельной операции пакетного запроса
+const (
+	opOK         = "ok"
+	opFailed     = "error"
+	opRolledBack = "rolled_back" // операция выполнилась, но отменена из-за ошибки другой
+	opSkipped    = "skipped"     // операция не выполнялась из-за ошибки другой
+)
+
+// BatchOperation - операция пакетного запроса.
+// Для create и update передаётся task, для update, delete и done - id
+// (для update его можно указать и в task). Version - ожидаемая версия задачи,
+// как в заголовке If-Match; 0 - без проверки.
+type BatchOperation struct {
+	Op      string     `json:"op"`
+	ID      string     `json:"id,omitempty"`
+	Version int        `json:"version,omitempty"`
+	Task    *TaskInput `json:"task,omitempty"`
+}
+
+// BatchRequest - тело запроса /api/tasks/batch
+type BatchRequest struct {
+	Mode       string           `json:"mode"`
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOpResult - результат операции; Index - номер операции в запросе (с 0),
+// Code - код ошибки, как в ответах одиночных запросов
+type BatchOpResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse - ответ на пакетный запрос
+type BatchResponse struct {
+	Code      string          `json:"code,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Mode      string          `json:"mode"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Results   []BatchOpResult `json:"results"`
+}
+
+// batchActions - действия журнала для операций пакетного запроса
+var batchActions = map[string]string{
+	db.BatchCreate: models.AuditCreate,
+	db.BatchUpdate: models.AuditUpdate,
+	db.BatchDelete: models.AuditDelete,
+	db.BatchDone:   models.AuditDone,
+}
+
+// HandleTaskBatch выполняет несколько операций с задачами в одной транзакции.
+// Каждая операция проверяется так же, как соответствующий одиночный запрос.
+// В режиме atomic (по умолчанию) ошибка любой операции отменяет все,
+// в режиме best_effort выполняются все корректные операции.
+func (h *Handler) HandleTaskBatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = batchAtomic
+	}
+	if req.Mode != batchAtomic && req.Mode != batchBestEffort {
+		writeError(w, r, errBatchModeInvalid)
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeError(w, r, errBatchEmpty)
+		return
+	}
+	if len(req.Operations) > maxBatchSize {
+		writeError(w, r, errBatchTooLarge.with(maxBatchSize))
+		return
+	}
+
+	lang := requestLanguage(r)
+	actor := actorFromRequest(r)
+	resp := BatchResponse{Mode: req.Mode, Results: make([]BatchOpResult, len(req.Operations))}
+	var ops []db.BatchOp
+	var indexes []int // номера операций из ops в запросе
+	for i, operation := range req.Operations {
+		resp.Results[i] = BatchOpResult{Index: i, Op: operation.Op}
+		op, e := h.prepareBatchOp(r.Context(), operation)
+		if e == nil {
+			// Запись журнала сохраняется в транзакции пакета вместе с операцией
+			rec, err := newAuditRecord(actor, batchActions[op.Action], op.Task.ID, nil, nil)
+			if err != nil {
+				e = errBatchFailed
+			}
+			op.Audit = &rec
+		}
+		if e != nil {
+			resp.Results[i].setError(e, lang)
+			continue
+		}
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	atomic := req.Mode == batchAtomic
+	if atomic && len(ops) < len(req.Operations) {
+		for _, i := range indexes {
+			resp.Results[i].Status = opSkipped
+		}
+		writeBatchResponse(w, r, errBatchInvalid, resp)
+		return
+	}
+
+	results, err := db.ExecBatch(r.Context(), h.DB, ops, atomic, utils.NormalizeDate(time.Now()))
+	if err != nil && !errors.Is(err, db.ErrBatchAborted) {
+		writeDBError(w, r, err, errBatchFailed)
+		return
+	}
+
+	for i := range ops {
+		result := &resp.Results[indexes[i]]
+		switch {
+		case i >= len(results):
+			result.Status = opSkipped
+		case results[i].Err != nil:
+			result.setError(batchError(results[i].Err, ops[i].Task.Version), lang)
+		case err != nil:
+			result.Status = opRolledBack
+		default:
+			result.Status = opOK
+			result.ID = ops[i].Task.ID
+			if ops[i].Action == db.BatchCreate {
+				result.ID = strconv.FormatInt(results[i].ID, 10)
+			}
+			removeAttachmentFiles(results[i].Paths)
+		}
+	}
+
+	var failure *apiError
+	if err != nil {
+		failure = errBatchAborted
+	}
+	writeBatchResponse(w, r, failure, resp)
+}
+
+// prepareBatchOp проверяет операцию по правилам одиночных запросов.
+// Текущее состояние задачи не читается: операция сверяется с ним
+// в транзакции пакета, после выполнения предыдущих операций.
+func (h *Handler) prepareBatchOp(ctx context.Context, operation BatchOperation) (db.BatchOp, *apiError) {
+	op := db.BatchOp{Action: operation.Op}
+
+	switch operation.Op {
+	case db.BatchCreate:
+		if operation.Task == nil {
+			return op, errBatchTaskMissing
+		}
+		task := operation.Task.task()
+		task.ID = ""
+		if e := h.prepareNewTask(ctx, &task); e != nil {
+			return op, e
+		}
+		op.Task = task
+		op.SetTags = len(task.Tags) > 0
+		return op, nil
+
+	case db.BatchUpdate:
+		if operation.Task == nil {
+			return op, errBatchTaskMissing
+		}
+		task := operation.Task.task()
+		if task.ID == "" {
+			task.ID = operation.ID
+		}
+		if _, e := validateTaskUpdate(&task); e != nil {
+			return op, e
+		}
+		if operation.Version == 0 && h.RequireIfMatch {
+			return op, errVersionRequired
+		}
+		// Существование проекта проверяется при обновлении, если проект меняется
+		if task.ProjectID != "" {
+			if _, err := strconv.Atoi(task.ProjectID); err != nil {
+				return op, errProjectIDInvalid
+			}
+		}
+
+		task.Version = operation.Version
+		op.Task = task
+		// Как и в PUT /api/task, непереданные теги, приоритет и проект остаются прежними
+		op.SetTags = task.Tags != nil
+		op.KeepPriority = operation.Task.Priority == nil
+		op.KeepProject = task.ProjectID == ""
+		return op, nil
+
+	case db.BatchDelete, db.BatchDone:
+		if operation.ID == "" {
+			return op, errTaskIDRequired
+		}
+		taskID, err := strconv.Atoi(operation.ID)
+		if err != nil {
+			return op, errTaskIDInvalid
+		}
+		if operation.Version == 0 && h.RequireIfMatch {
+			return op, errVersionRequired
+		}
+		op.Task = models.Task{ID: strconv.Itoa(taskID), Version: operation.Version}
+		return op, nil
+
+	default:
+		return op, errBatchOpUnknown
+	}
+}
+
+// setError отмечает операцию ошибочной с сообщением на языке lang
+func (result *BatchOpResult) setError(e *apiError, lang string) {
+	result.Status = opFailed
+	result.Code = e.Code
+	result.Error = e.message(lang)
+}
+
+// batchError возвращает ошибку API для ошибки базы данных при выполнении операции
+// с ожидаемой версией задачи version (0 - без проверки)
+func batchError(err error, version int) *apiError {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return errTaskNotFound
+	case errors.Is(err, db.ErrTaskConflict) && version != 0:
+		return errPreconditionFailed
+	case errors.Is(err, db.ErrTaskConflict):
+		return errTaskConflict
+	case errors.Is(err, db.ErrStoredRepeatInvalid):
+		return errStoredRepeatInvalid
+	default:
+		return dbError(err, errBatchFailed)
+	}
+}
+
+// writeBatchResponse подсчитывает результаты и отправляет ответ на пакетный запрос.
+// Если запрос не выполнен, failure задаёт код ответа и ошибку всего пакета.
+func writeBatchResponse(w http.ResponseWriter, r *http.Request, failure *apiError, resp BatchResponse) {
+	for _, result := range resp.Results {
+		switch result.Status {
+		case opOK:
+			resp.Succeeded++
+		case opFailed:
+			resp.Failed++
+		}
+	}
+
+	status := http.StatusOK
+	if failure != nil {
+		status = failure.Status
+		resp.Code = failure.Code
+		resp.Error = failure.message(requestLanguage(r))
+		log.Printf("[ERROR] %s: %s", resp.Code, failure.Error())
+	}
+
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("[ERROR] batch response: %v", err)
+	}
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_final_project/constants"
+	"go_final_project/db"
+	"go_final_project/ical"
+	"go_final_project/models"
+	"go_final_project/utils"
+)
+
+// Компоненты, в виде которых задачи попадают в календарь
+const (
+	componentEvent = "vevent" // событие на весь день (поддерживается всеми календарями)
+	componentTodo  = "vtodo"  // задача со сроком (Thunderbird и другие клиенты с задачами)
+)
+
+// calendarFeedPath - префикс адреса подписки; за ним следует токен и ".ics"
+const calendarFeedPath = "/api/calendar/feed/"
+
+// icalTimeFormat - формат даты и времени UTC в iCalendar
+const icalTimeFormat = "20060102T150405Z"
+
+// HandleCalendar отдаёт все задачи одним файлом .ics (?component=vevent|vtodo)
+func (h *Handler) HandleCalendar(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	component, ok := calendarComponent(w, r)
+	if !ok {
+		return
+	}
+
+	filename := "tasks-" + time.Now().Format("20060102") + ".ics"
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	h.writeCalendar(r.Context(), w, component)
+}
+
+// HandleCalendarFeed отдаёт календарь для подписки по адресу /api/calendar/feed/<токен>.ics.
+// Без токена (TODO_CALENDAR_TOKEN) подписка отключена.
+func (h *Handler) HandleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+	if h.CalendarToken == "" {
+		writeError(w, r, errCalendarDisabled)
+		return
+	}
+
+	token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, calendarFeedPath), ".ics")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.CalendarToken)) != 1 {
+		writeError(w, r, errCalendarNotFound)
+		return
+	}
+
+	component, ok := calendarComponent(w, r)
+	if !ok {
+		return
+	}
+
+	// Клиенты опрашивают подписку сами, кэш промежуточных прокси не нужен
+	w.Header().Set("Cache-Control", "no-store")
+	h.writeCalendar(r.Context(), w, component)
+}
+
+// calendarComponent читает параметр component (по умолчанию vevent)
+func calendarComponent(w http.ResponseWriter, r *http.Request) (string, bool) {
+	component := strings.ToLower(r.URL.Query().Get("component"))
+	switch component {
+	case "":
+		return componentEvent, true
+	case componentEvent, componentTodo:
+		return component, true
+	}
+	writeError(w, r, errCalendarComponent)
+	return "", false
+}
+
+// writeCalendar пишет календарь потоком; задачи архивных проектов не попадают в него
+func (h *Handler) writeCalendar(ctx context.Context, w http.ResponseWriter, component string) {
+	w.Header().Set("Content-Type", "text/calendar; charset=UTF-8")
+
+	cal := ical.NewWriter(w)
+	cal.Begin("VCALENDAR")
+	cal.Property("VERSION", "2.0")
+	cal.Property("PRODID", "-//go_final_project//scheduler//RU")
+	cal.Property("CALSCALE", "GREGORIAN")
+	cal.Property("METHOD", "PUBLISH")
+	cal.Text("X-WR-CALNAME", "Планировщик задач")
+
+	stamp := time.Now().UTC().Format(icalTimeFormat)
+	filter := db.TaskFilter{Sort: db.DefaultSort, Limit: -1}
+	err := db.ForEachTask(ctx, h.DB, filter, func(task models.Task) error {
+		writeCalendarTask(cal, task, component, stamp)
+		return nil
+	})
+	cal.End("VCALENDAR")
+
+	if err == nil {
+		err = cal.Flush()
+	}
+	// Заголовки уже отправлены, поэтому ошибку можно только записать в лог
+	if err != nil {
+		log.Printf("[ERROR] calendar: %v", err)
+	}
+}
+
+// writeCalendarTask пишет задачу как событие на весь день или как задачу со сроком.
+// Исходное правило повторения сохраняется в X-SCHEDULER-REPEAT, даже если для него
+// нет аналога в RRULE.
+func writeCalendarTask(cal *ical.Writer, task models.Task, component, stamp string) {
+	name := strings.ToUpper(component)
+	cal.Begin(name)
+	cal.Property("UID", "task-"+task.ID+"@go_final_project")
+	cal.Property("DTSTAMP", stamp)
+	if created, err := time.Parse(time.RFC3339, task.CreatedAt); err == nil {
+		cal.Property("CREATED", created.UTC().Format(icalTimeFormat))
+	}
+	cal.Property("DTSTART;VALUE=DATE", task.Date)
+	if component == componentTodo {
+		cal.Property("DUE;VALUE=DATE", task.Date)
+		cal.Property("STATUS", "NEEDS-ACTION")
+	} else if date, err := time.Parse(constants.DateFormat, task.Date); err == nil {
+		cal.Property("DTEND;VALUE=DATE", date.AddDate(0, 0, 1).Format(constants.DateFormat))
+		cal.Property("TRANSP", "TRANSPARENT")
+	}
+	cal.Text("SUMMARY", task.Title)
+	if task.Comment != "" {
+		cal.Text("DESCRIPTION", task.Comment)
+	}
+	if len(task.Tags) > 0 {
+		tags := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			tags[i] = ical.EscapeText(tag)
+		}
+		cal.Property("CATEGORIES", strings.Join(tags, ","))
+	}
+	if priority := icalPriority(task.Priority); priority != "" {
+		cal.Property("PRIORITY", priority)
+	}
+	if task.Repeat != "" {
+		if rule, ok := ical.RepeatToRRule(task.Repeat); ok {
+			cal.Property("RRULE", rule)
+		}
+		cal.Text("X-SCHEDULER-REPEAT", task.Repeat)
+	}
+	cal.End(name)
+}
+
+// icalPriority переводит приоритет задачи (3 - самый высокий) в шкалу iCalendar,
+// где 1 - самый высокий, 9 - самый низкий, а 0 означает отсутствие приоритета
+func icalPriority(priority int) string {
+	switch priority {
+	case 3:
+		return "1"
+	case 2:
+		return "5"
+	case 1:
+		return "9"
+	}
+	return ""
+}
+
+// CalendarImportItem - результат импорта одной записи календаря (записи нумеруются с 1)
+type CalendarImportItem struct {
+	Item    int    `json:"item"`
+	UID     string `json:"uid,omitempty"`
+	Title   string `json:"title"`
+	Status  string `json:"status"`
+	ID      string `json:"id,omitempty"`
+	Repeat  string `json:"repeat,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// CalendarImportReport - результат импорта календаря
+type CalendarImportReport struct {
+	DryRun   bool                 `json:"dry_run"`
+	Total    int                  `json:"total"`
+	Imported int                  `json:"imported"`
+	Skipped  int                  `json:"skipped"`
+	Failed   int                  `json:"failed"`
+	Items    []CalendarImportItem `json:"items"`
+}
+
+// HandleCalendarImport загружает задачи из файла .ics (записи VEVENT и VTODO).
+// Записи проверяются по тем же правилам, что и при добавлении через /api/task;
+// корректные записи добавляются, по каждой записи возвращается результат.
+// Правила повторения, которые нельзя перевести, отбрасываются с предупреждением.
+// Параметр dry_run=1 только проверяет файл.
+func (h *Handler) HandleCalendarImport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	cal, err := ical.Parse(r.Body)
+	if err != nil {
+		writeError(w, r, errCalendarParse)
+		return
+	}
+
+	report := CalendarImportReport{
+		DryRun: r.URL.Query().Get("dry_run") == "1",
+		Items:  []CalendarImportItem{},
+	}
+	lang := requestLanguage(r)
+	var tasks []models.Task
+	var imported []int // индексы в report.Items для добавляемых задач
+	for _, c := range cal.Components {
+		if c.Name != "VEVENT" && c.Name != "VTODO" {
+			continue
+		}
+
+		report.Total++
+		item := CalendarImportItem{Item: report.Total, UID: c.Text("UID"), Title: c.Text("SUMMARY")}
+		task, rule, err := calendarTask(c)
+		var e *apiError
+		switch {
+		case errors.Is(err, errCalendarClosed):
+			item.Status = itemSkipped
+			item.Warning = message(lang, calendarClosedWarning)
+			report.Skipped++
+		case errors.As(err, &e):
+			item.Status = itemFailed
+			item.Code, item.Error = e.Code, e.message(lang)
+			report.Failed++
+		case err != nil:
+			item.Status = itemFailed
+			item.Code, item.Error = errCalendarParse.Code, errCalendarParse.message(lang)
+			report.Failed++
+		default:
+			if e := h.prepareNewTask(r.Context(), &task); e != nil {
+				item.Status = itemFailed
+				item.Code, item.Error = e.Code, e.message(lang)
+				report.Failed++
+				break
+			}
+			item.Status = itemImported
+			item.Repeat = task.Repeat
+			if rule != "" {
+				item.Warning = message(lang, repeatWarning, rule)
+			}
+			tasks = append(tasks, task)
+			imported = append(imported, len(report.Items))
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	if !report.DryRun && len(tasks) > 0 {
+		audit, err := newAuditRecord(actorFromRequest(r), "", "", nil, nil)
+		if err != nil {
+			writeError(w, r, errImportFailed)
+			return
+		}
+		ids, _, err := db.ImportTasks(r.Context(), h.DB, tasks, nil, false, audit)
+		if err != nil {
+			writeDBError(w, r, err, errImportFailed)
+			return
+		}
+		for i, id := range ids {
+			report.Items[imported[i]].ID = strconv.FormatInt(id, 10)
+		}
+	}
+	report.Imported = len(tasks)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("[ERROR] calendar import report: %v", err)
+	}
+}
+
+// errCalendarClosed - запись выполнена или отменена, импортировать её не нужно
+var errCalendarClosed = errors.New("calendar entry is completed or cancelled")
+
+// calendarTask переводит запись календаря в задачу.
+// Второе значение - правила повторения, которые не удалось перенести:
+// некорректное X-SCHEDULER-REPEAT и RRULE без аналога.
+func calendarTask(c *ical.Component) (models.Task, string, error) {
+	status := strings.ToUpper(c.Text("STATUS"))
+	if status == "COMPLETED" || status == "CANCELLED" {
+		return models.Task{}, "", errCalendarClosed
+	}
+
+	task := models.Task{Title: c.Text("SUMMARY"), Comment: c.Text("DESCRIPTION")}
+
+	prop, ok := c.Get("DTSTART")
+	if !ok {
+		prop, ok = c.Get("DUE")
+	}
+	if ok {
+		var err error
+		task.Date, err = calendarDate(prop.Value)
+		if err != nil {
+			return models.Task{}, "", err
+		}
+	}
+
+	for _, category := range c.Properties {
+		if category.Name != "CATEGORIES" {
+			continue
+		}
+		for _, tag := range ical.SplitList(category.Value) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		}
+	}
+
+	if prop, ok := c.Get("PRIORITY"); ok {
+		task.Priority = taskPriority(prop.Value)
+	}
+
+	// Правило, выгруженное этим же приложением, переносится без потерь.
+	// Файл могли изменить вручную, поэтому некорректное правило отбрасывается
+	// и используется перевод RRULE
+	var skipped []string
+	if repeat := c.Text("X-SCHEDULER-REPEAT"); repeat != "" {
+		date := task.Date
+		if date == "" {
+			date = time.Now().Format(constants.DateFormat)
+		}
+		if _, err := utils.NextDate(utils.NormalizeDate(time.Now()), date, repeat); err == nil {
+			task.Repeat = repeat
+			return task, "", nil
+		}
+		skipped = append(skipped, repeat)
+	}
+
+	if prop, ok := c.Get("RRULE"); ok {
+		repeat, err := ical.RRuleToRepeat(prop.Value)
+		if err != nil {
+			skipped = append(skipped, prop.Value)
+		}
+		task.Repeat = repeat
+	}
+	return task, strings.Join(skipped, ", "), nil
+}
+
+// calendarDate возвращает дату задачи из значения DATE или DATE-TIME.
+// Время в UTC переводится в местное, время с часовым поясом берётся как есть.
+func calendarDate(value string) (string, error) {
+	if t, err := time.Parse(icalTimeFormat, value); err == nil {
+		return t.Local().Format(constants.DateFormat), nil
+	}
+	if len(value) >= len(constants.DateFormat) {
+		date := value[:len(constants.DateFormat)]
+		if _, err := time.Parse(constants.DateFormat, date); err == nil {
+			return date, nil
+		}
+	}
+	return "", errCalendarEntryDate.with(value)
+}
+
+// taskPriority переводит приоритет iCalendar (1 - самый высокий, 9 - самый низкий)
+// в приоритет задачи, обратно к icalPriority
+func taskPriority(value string) int {
+	priority, err := strconv.Atoi(value)
+	switch {
+	case err != nil || priority <= 0 || priority > 9:
+		return 0
+	case priority < 5:
+		return 3
+	case priority == 5:
+		return 2
+	default:
+		return 1
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"go_final_project/constants"
 	"go_final_project/utils"
 )
 
@@ -15,13 +16,18 @@ func HandleDate(w http.ResponseWriter, r *http.Request) {
 
 	now, err := time.Parse("20060102", nowStr)
 	if err != nil {
-		http.Error(w, "Invalid now parameter", http.StatusBadRequest)
+		writeError(w, r, errNowInvalid)
 		return
 	}
 
 	nextDate, err := utils.NextDate(now, dateStr, repeat)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		// Текст ошибки NextDate не переводится, поэтому сообщаем, какой параметр неверен
+		if _, err := time.Parse(constants.DateFormat, dateStr); err != nil {
+			writeError(w, r, errDateInvalid)
+		} else {
+			writeError(w, r, errRepeatInvalid)
+		}
 		return
 	}
 
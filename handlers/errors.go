@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"go_final_project/db"
+)
+
+// problemContentType - тип содержимого ответа с ошибкой (RFC 9457)
+const problemContentType = "application/problem+json; charset=UTF-8"
+
+// Problem - тело ответа с ошибкой в формате RFC 9457.
+// Code - стабильный машиночитаемый код, на который могут опираться клиенты;
+// Error повторяет Detail для клиентов, которые читают прежнее поле error.
+// Необязательное поле status не передаётся: код есть в самом ответе,
+// а все поля остаются строками, как в прежнем формате {"error": "..."}.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+	Code   string `json:"code"`
+	Error  string `json:"error"`
+}
+
+// apiError - ошибка API: код ответа и стабильный код, по которому сообщение
+// берётся из каталога на языке клиента. Сообщение может содержать параметры
+// в формате fmt, которые подставляет with.
+type apiError struct {
+	Status int
+	Code   string
+	args   []any
+}
+
+// Error возвращает текст сообщения на языке по умолчанию
+func (e *apiError) Error() string {
+	return e.message(defaultLanguage)
+}
+
+// message возвращает текст сообщения на языке lang с подставленными параметрами
+func (e *apiError) message(lang string) string {
+	return message(lang, e.Code, e.args...)
+}
+
+// with возвращает копию ошибки с параметрами сообщения
+func (e *apiError) with(args ...any) *apiError {
+	c := *e
+	c.args = args
+	return &c
+}
+
+// newError описывает ошибку API; сообщения для кода хранятся в каталогах messages_*.go
+func newError(status int, code string) *apiError {
+	return &apiError{Status: status, Code: code}
+}
+
+// Общие ошибки
+var (
+	errInvalidJSON          = newError(http.StatusBadRequest, "invalid_json")
+	errMethodNotAllowed     = newError(http.StatusMethodNotAllowed, "method_not_allowed")
+	errUnsupportedMediaType = newError(http.StatusUnsupportedMediaType, "unsupported_media_type")
+	errEncodeResponse       = newError(http.StatusInternalServerError, "response_encoding_failed")
+	errInternal             = newError(http.StatusInternalServerError, "internal_error")
+	errServiceUnavailable   = newError(http.StatusServiceUnavailable, "service_unavailable")
+)
+
+// Ошибки задач
+var (
+	errTaskIDRequired       = newError(http.StatusUnprocessableEntity, "task_id_required")
+	errTaskIDInvalid        = newError(http.StatusUnprocessableEntity, "task_id_invalid")
+	errTaskIDImmutable      = newError(http.StatusUnprocessableEntity, "task_id_immutable")
+	errTaskNotFound         = newError(http.StatusNotFound, "task_not_found")
+	errDateInvalid          = newError(http.StatusUnprocessableEntity, "invalid_date")
+	errRepeatInvalid        = newError(http.StatusUnprocessableEntity, "invalid_repeat")
+	errTitleRequired        = newError(http.StatusUnprocessableEntity, "title_required")
+	errCommentInvalid       = newError(http.StatusUnprocessableEntity, "invalid_comment")
+	errTagsInvalid          = newError(http.StatusUnprocessableEntity, "invalid_tags")
+	errPriorityInvalid      = newError(http.StatusUnprocessableEntity, "invalid_priority")
+	errFieldReadOnly        = newError(http.StatusUnprocessableEntity, "field_read_only")
+	errFieldUnknown         = newError(http.StatusUnprocessableEntity, "unknown_field")
+	errNowInvalid           = newError(http.StatusUnprocessableEntity, "invalid_now")
+	errCreatedAtInvalid     = newError(http.StatusUnprocessableEntity, "invalid_created_at")
+	errTaskConflict         = newError(http.StatusConflict, "task_conflict")
+	errStoredRepeatInvalid  = newError(http.StatusUnprocessableEntity, "stored_repeat_invalid")
+	errPreconditionFailed   = newError(http.StatusPreconditionFailed, "precondition_failed")
+	errPreconditionRequired = newError(http.StatusPreconditionRequired, "precondition_required")
+	errVersionRequired      = newError(http.StatusPreconditionRequired, "version_required")
+	errTaskCreateFailed     = newError(http.StatusInternalServerError, "task_create_failed")
+	errTaskUpdateFailed     = newError(http.StatusInternalServerError, "task_update_failed")
+	errTaskDeleteFailed     = newError(http.StatusInternalServerError, "task_delete_failed")
+	errTaskDoneFailed       = newError(http.StatusInternalServerError, "task_done_failed")
+	errTaskMoveFailed       = newError(http.StatusInternalServerError, "task_move_failed")
+)
+
+// Ошибки списка задач
+var (
+	errTagFilterInvalid     = newError(http.StatusUnprocessableEntity, "invalid_tag_filter")
+	errProjectFilterInvalid = newError(http.StatusUnprocessableEntity, "invalid_project_filter")
+	errSortInvalid          = newError(http.StatusUnprocessableEntity, "invalid_sort")
+	errDateFilterInvalid    = newError(http.StatusUnprocessableEntity, "invalid_date_filter")
+	errDateRangeInvalid     = newError(http.StatusUnprocessableEntity, "invalid_date_range")
+	errOverdueInvalid       = newError(http.StatusUnprocessableEntity, "invalid_overdue")
+	errRepeatFilterInvalid  = newError(http.StatusUnprocessableEntity, "invalid_repeat_filter")
+	errHasCommentInvalid    = newError(http.StatusUnprocessableEntity, "invalid_has_comment")
+	errTagModeInvalid       = newError(http.StatusUnprocessableEntity, "invalid_tag_mode")
+	errCursorInvalid        = newError(http.StatusUnprocessableEntity, "invalid_cursor")
+	errCursorSort           = newError(http.StatusUnprocessableEntity, "cursor_requires_date_sort")
+	errTaskListFailed       = newError(http.StatusInternalServerError, "task_list_failed")
+)
+
+// Ошибки проектов
+var (
+	errProjectIDRequired      = newError(http.StatusUnprocessableEntity, "project_id_required")
+	errProjectIDInvalid       = newError(http.StatusUnprocessableEntity, "project_id_invalid")
+	errProjectNotFound        = newError(http.StatusNotFound, "project_not_found")
+	errProjectUnknown         = newError(http.StatusUnprocessableEntity, "unknown_project")
+	errProjectArchived        = newError(http.StatusUnprocessableEntity, "project_archived")
+	errProjectNameRequired    = newError(http.StatusUnprocessableEntity, "project_name_required")
+	errProjectColorInvalid    = newError(http.StatusUnprocessableEntity, "invalid_color")
+	errProjectDeleteMode      = newError(http.StatusUnprocessableEntity, "invalid_delete_mode")
+	errProjectListFailed      = newError(http.StatusInternalServerError, "project_list_failed")
+	errProjectCreateFailed    = newError(http.StatusInternalServerError, "project_create_failed")
+	errProjectUpdateFailed    = newError(http.StatusInternalServerError, "project_update_failed")
+	errProjectDeleteFailed    = newError(http.StatusInternalServerError, "project_delete_failed")
+	errSubtaskIDInvalid       = newError(http.StatusUnprocessableEntity, "subtask_id_invalid")
+	errSubtaskTitleRequired   = newError(http.StatusUnprocessableEntity, "subtask_title_required")
+	errSubtaskNotFound        = newError(http.StatusNotFound, "subtask_not_found")
+	errSubtaskOrderInvalid    = newError(http.StatusUnprocessableEntity, "invalid_subtask_order")
+	errSubtaskListFailed      = newError(http.StatusInternalServerError, "subtask_list_failed")
+	errSubtaskCreateFailed    = newError(http.StatusInternalServerError, "subtask_create_failed")
+	errSubtaskUpdateFailed    = newError(http.StatusInternalServerError, "subtask_update_failed")
+	errSubtaskReorderFailed   = newError(http.StatusInternalServerError, "subtask_reorder_failed")
+	errSubtaskDeleteFailed    = newError(http.StatusInternalServerError, "subtask_delete_failed")
+	errAttachmentIDInvalid    = newError(http.StatusUnprocessableEntity, "attachment_id_invalid")
+	errAttachmentNotFound     = newError(http.StatusNotFound, "attachment_not_found")
+	errAttachmentFileRequired = newError(http.StatusUnprocessableEntity, "file_required")
+	errAttachmentFileRead     = newError(http.StatusBadRequest, "file_unreadable")
+	errAttachmentTooLarge     = newError(http.StatusRequestEntityTooLarge, "file_too_large")
+	errAttachmentSaveFailed   = newError(http.StatusInternalServerError, "file_save_failed")
+	errAttachmentListFailed   = newError(http.StatusInternalServerError, "attachment_list_failed")
+	errAttachmentCreateFailed = newError(http.StatusInternalServerError, "attachment_create_failed")
+	errAttachmentReadFailed   = newError(http.StatusInternalServerError, "attachment_read_failed")
+	errAttachmentDeleteFailed = newError(http.StatusInternalServerError, "attachment_delete_failed")
+)
+
+// Ошибки журнала изменений
+var (
+	errAuditIDRequired = newError(http.StatusUnprocessableEntity, "audit_id_required")
+	errAuditIDInvalid  = newError(http.StatusUnprocessableEntity, "audit_id_invalid")
+	errAuditNotFound   = newError(http.StatusNotFound, "audit_record_not_found")
+	errAuditOtherTask  = newError(http.StatusUnprocessableEntity, "audit_record_mismatch")
+	errAuditNoState    = newError(http.StatusUnprocessableEntity, "audit_record_empty")
+	errAuditCorrupt    = newError(http.StatusInternalServerError, "audit_record_corrupt")
+	errHistoryFailed   = newError(http.StatusInternalServerError, "history_failed")
+	errRestoreFailed   = newError(http.StatusInternalServerError, "restore_failed")
+)
+
+// Ошибки пакетных операций
+var (
+	errBatchModeInvalid = newError(http.StatusUnprocessableEntity, "invalid_batch_mode")
+	errBatchEmpty       = newError(http.StatusUnprocessableEntity, "batch_empty")
+	errBatchTooLarge    = newError(http.StatusUnprocessableEntity, "batch_too_large")
+	errBatchInvalid     = newError(http.StatusUnprocessableEntity, "batch_invalid")
+	errBatchAborted     = newError(http.StatusConflict, "batch_aborted")
+	errBatchTaskMissing = newError(http.StatusUnprocessableEntity, "batch_task_required")
+	errBatchOpUnknown   = newError(http.StatusUnprocessableEntity, "unknown_batch_op")
+	errBatchFailed      = newError(http.StatusInternalServerError, "batch_failed")
+)
+
+// Ошибки импорта, экспорта и календаря
+var (
+	errFormatInvalid     = newError(http.StatusUnprocessableEntity, "invalid_format")
+	errImportModeInvalid = newError(http.StatusUnprocessableEntity, "invalid_import_mode")
+	errImportParse       = newError(http.StatusUnprocessableEntity, "import_parse_failed")
+	errImportDuplicateID = newError(http.StatusUnprocessableEntity, "duplicate_task_id")
+	errImportInvalid     = newError(http.StatusUnprocessableEntity, "import_invalid")
+	errImportFailed      = newError(http.StatusInternalServerError, "import_failed")
+	errCalendarComponent = newError(http.StatusUnprocessableEntity, "invalid_calendar_component")
+	errCalendarParse     = newError(http.StatusUnprocessableEntity, "calendar_parse_failed")
+	errCalendarDisabled  = newError(http.StatusForbidden, "calendar_feed_disabled")
+	errCalendarNotFound  = newError(http.StatusNotFound, "calendar_not_found")
+	errCalendarEntryDate = newError(http.StatusUnprocessableEntity, "invalid_calendar_date")
+	errEntryParse        = newError(http.StatusUnprocessableEntity, "entry_parse_failed")
+)
+
+// Коды предупреждений импорта в каталогах сообщений: запись пропускается
+// или импортируется не полностью, но ошибкой не считается
+const (
+	calendarClosedWarning = "calendar_entry_closed" // запись календаря выполнена или отменена
+	repeatWarning         = "repeat_not_converted"  // правило повторения без аналога
+	projectWarning        = "first_project_only"    // у строки todo.txt несколько проектов
+)
+
+// Ошибки административного API
+var (
+	errAdminDisabled     = newError(http.StatusForbidden, "admin_disabled")
+	errAdminUnauthorized = newError(http.StatusUnauthorized, "admin_unauthorized")
+	errReencryptFailed   = newError(http.StatusInternalServerError, "reencrypt_failed")
+	errBackupFailed      = newError(http.StatusInternalServerError, "backup_failed")
+	errBackupReadFailed  = newError(http.StatusInternalServerError, "backup_read_failed")
+)
+
+// writeError отправляет ошибку в формате application/problem+json
+// на языке, выбранном для запроса
+func writeError(w http.ResponseWriter, r *http.Request, e *apiError) {
+	log.Printf("[ERROR] %s: %s", e.Code, e.Error())
+
+	lang := requestLanguage(r)
+	text := e.message(lang)
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("Content-Language", lang)
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(e.Status),
+		Detail: text,
+		Code:   e.Code,
+		Error:  text,
+	})
+}
+
+// writeMethodNotAllowed отвечает 405 и перечисляет допустимые методы в заголовке Allow
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, r, errMethodNotAllowed)
+}
+
+// MethodNotAllowed возвращает обработчик, который отвечает 405 с заголовком Allow.
+// Регистрируется на путь без метода рядом с маршрутами вида "GET /путь".
+func MethodNotAllowed(allowed ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeMethodNotAllowed(w, r, allowed...)
+	}
+}
+
+// dbError возвращает ошибку API для ошибки базы данных. Если запрос не уложился
+// в отведённое время или база осталась заблокированной после всех повторов, это 503:
+// клиент может повторить запрос позже. Ошибка «не найдено» (404) возвращается, только
+// если запись действительно не найдена; прочие ошибки базы в этом случае становятся 500.
+func dbError(err error, e *apiError) *apiError {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, db.ErrBusy):
+		return errServiceUnavailable
+	case errors.Is(err, db.ErrProjectUnknown):
+		return errProjectUnknown
+	case errors.Is(err, db.ErrProjectArchived):
+		return errProjectArchived
+	case e.Status == http.StatusNotFound && !errors.Is(err, db.ErrNotFound):
+		log.Printf("[ERROR] %v", err)
+		return errInternal
+	default:
+		if e.Status >= http.StatusInternalServerError {
+			log.Printf("[ERROR] %v", err)
+		}
+		return e
+	}
+}
+
+// writeDBError сообщает об ошибке базы данных (см. dbError)
+func writeDBError(w http.ResponseWriter, r *http.Request, err error, e *apiError) {
+	e = dbError(err, e)
+	if e == errServiceUnavailable {
+		w.Header().Set("Retry-After", "1")
+	}
+	writeError(w, r, e)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// formatETag возвращает значение заголовка ETag для версии задачи
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// checkIfMatch сверяет заголовок If-Match с текущей версией задачи и возвращает
+// версию, при которой изменение допустимо (0 - без условия).
+// При несовпадении отправляет 412, а при отсутствии обязательного заголовка - 428,
+// после чего возвращает false.
+func (h *Handler) checkIfMatch(w http.ResponseWriter, r *http.Request, current int) (int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		if h.RequireIfMatch {
+			writeError(w, r, errPreconditionRequired)
+			return 0, false
+		}
+		return 0, true
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	// Заголовок может содержать несколько значений: "1", "2".
+	// If-Match требует строгого сравнения (RFC 7232, 3.1), поэтому слабые
+	// значения вида W/"2" не совпадают ни с одной версией.
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == formatETag(current) {
+			return current, true
+		}
+	}
+
+	writeError(w, r, errPreconditionFailed)
+	return 0, false
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_final_project/db"
+	"go_final_project/models"
+)
+
+// Форматы импорта и экспорта
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+// Режимы импорта
+const (
+	importMerge   = "merge"   // задачи добавляются к существующим с новыми ID
+	importReplace = "replace" // существующие задачи удаляются, ID сохраняются
+)
+
+// Результаты импорта отдельной записи (записи календаря или строки todo.txt)
+const (
+	itemImported = "imported" // запись добавлена как задача
+	itemSkipped  = "skipped"  // запись выполнена или отменена
+	itemFailed   = "error"    // запись не прошла проверку
+)
+
+// maxImportSize - максимальный размер загружаемого файла импорта
+const maxImportSize = 10 << 20
+
+// csvColumns - колонки CSV при экспорте; при импорте порядок колонок берётся из заголовка
+var csvColumns = []string{"id", "date", "title", "comment", "repeat", "tags", "project_id", "priority", "created_at"}
+
+// ImportRowError - ошибка проверки одной задачи при импорте (строки нумеруются с 1)
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// ImportReport - результат импорта задач
+type ImportReport struct {
+	Code     string           `json:"code,omitempty"`
+	Error    string           `json:"error,omitempty"`
+	Mode     string           `json:"mode"`
+	DryRun   bool             `json:"dry_run"`
+	Total    int              `json:"total"`
+	Valid    int              `json:"valid"`
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// HandleExport выгружает все задачи в формате JSON или CSV (?format=json|csv)
+func (h *Handler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
+
+	filter := db.TaskFilter{IncludeArchived: true, Sort: []db.SortField{{Key: "date"}}, Limit: -1}
+	filename := "tasks-" + time.Now().Format("20060102") + "." + format
+
+	var err error
+	switch format {
+	case formatJSON:
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		err = h.exportJSON(r.Context(), w, filter)
+	case formatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		err = h.exportCSV(r.Context(), w, filter)
+	default:
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		writeError(w, r, errFormatInvalid)
+		return
+	}
+
+	// Заголовки уже отправлены, поэтому ошибку можно только записать в лог
+	if err != nil {
+		log.Printf("[ERROR] export: %v", err)
+	}
+}
+
+// exportJSON пишет задачи потоком в виде {"tasks":[...]}
+func (h *Handler) exportJSON(ctx context.Context, w io.Writer, filter db.TaskFilter) error {
+	if _, err := io.WriteString(w, `{"tasks":[`); err != nil {
+		return err
+	}
+
+	first := true
+	err := db.ForEachTask(ctx, h.DB, filter, func(task models.Task) error {
+		task.Checklist = nil
+		data, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// exportCSV пишет задачи потоком в CSV с заголовком; теги перечисляются через запятую
+func (h *Handler) exportCSV(ctx context.Context, w io.Writer, filter db.TaskFilter) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvColumns); err != nil {
+		return err
+	}
+
+	err := db.ForEachTask(ctx, h.DB, filter, func(task models.Task) error {
+		return cw.Write([]string{
+			task.ID, task.Date, task.Title, task.Comment, task.Repeat,
+			strings.Join(task.Tags, ","), task.ProjectID,
+			strconv.Itoa(task.Priority), task.CreatedAt,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// HandleImport загружает задачи из JSON или CSV.
+// Параметры: format=json|csv, mode=merge|replace, dry_run=1 (только проверка).
+// Каждая задача проверяется по тем же правилам, что и при добавлении через /api/task;
+// при ошибке хотя бы в одной строке ничего не импортируется.
+func (h *Handler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	query := r.URL.Query()
+	report := ImportReport{
+		Mode:   query.Get("mode"),
+		DryRun: query.Get("dry_run") == "1",
+		Errors: []ImportRowError{},
+	}
+	if report.Mode == "" {
+		report.Mode = importMerge
+	}
+	if report.Mode != importMerge && report.Mode != importReplace {
+		writeError(w, r, errImportModeInvalid)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var tasks []models.Task
+	var err error
+	switch format := query.Get("format"); format {
+	case "", formatJSON:
+		tasks, err = parseImportJSON(r.Body)
+	case formatCSV:
+		tasks, err = parseImportCSV(r.Body)
+	default:
+		writeError(w, r, errFormatInvalid)
+		return
+	}
+	if err != nil {
+		writeError(w, r, errImportParse)
+		return
+	}
+
+	lang := requestLanguage(r)
+	report.Total = len(tasks)
+	replace := report.Mode == importReplace
+	seen := make(map[string]bool)
+	for i := range tasks {
+		task := &tasks[i]
+		task.Checklist = nil
+		if !replace {
+			task.ID = ""
+		}
+
+		e := h.prepareImportedTask(r.Context(), task)
+		if e == nil && task.ID != "" {
+			// При замене ID сохраняются, поэтому они должны быть корректными и уникальными.
+			// ID приводится к каноническому виду, иначе "1" и "01" совпали бы только в базе
+			if id, err := strconv.Atoi(task.ID); err != nil || id <= 0 {
+				e = errTaskIDInvalid
+			} else if task.ID = strconv.Itoa(id); seen[task.ID] {
+				e = errImportDuplicateID
+			}
+			seen[task.ID] = true
+		}
+		if e != nil {
+			report.Errors = append(report.Errors, ImportRowError{Row: i + 1, Code: e.Code, Error: e.message(lang)})
+		}
+	}
+	report.Valid = report.Total - len(report.Errors)
+
+	if report.DryRun {
+		writeImportReport(w, r, nil, report)
+		return
+	}
+	if len(report.Errors) > 0 {
+		writeImportReport(w, r, errImportInvalid, report)
+		return
+	}
+
+	audit, err := newAuditRecord(actorFromRequest(r), "", "", nil, nil)
+	if err != nil {
+		writeError(w, r, errImportFailed)
+		return
+	}
+	ids, paths, err := db.ImportTasks(r.Context(), h.DB, tasks, nil, replace, audit)
+	if err != nil {
+		writeDBError(w, r, err, errImportFailed)
+		return
+	}
+	report.Imported = len(ids)
+
+	// Файлы вложений удалённых задач удаляются только после успешной замены
+	removeAttachmentFiles(paths)
+
+	writeImportReport(w, r, nil, report)
+}
+
+// writeImportReport отправляет результат импорта.
+// Если задачи не импортированы, failure задаёт код ответа и ошибку импорта.
+func writeImportReport(w http.ResponseWriter, r *http.Request, failure *apiError, report ImportReport) {
+	status := http.StatusOK
+	if failure != nil {
+		status = failure.Status
+		report.Code = failure.Code
+		report.Error = failure.message(requestLanguage(r))
+		log.Printf("[ERROR] %s: %s", report.Code, failure.Error())
+	}
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("[ERROR] import report: %v", err)
+	}
+}
+
+// parseImportJSON разбирает задачи в формате экспорта {"tasks":[...]}
+func parseImportJSON(r io.Reader) ([]models.Task, error) {
+	var data struct {
+		Tasks []models.Task `json:"tasks"`
+	}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data.Tasks, nil
+}
+
+// parseImportCSV разбирает задачи из CSV с заголовком.
+// Неизвестные колонки игнорируются, отсутствующие считаются пустыми.
+func parseImportCSV(r io.Reader) ([]models.Task, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("missing column 'title'")
+	}
+
+	var tasks []models.Task
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+
+		task := models.Task{
+			ID:        field("id"),
+			Date:      field("date"),
+			Title:     field("title"),
+			Comment:   field("comment"),
+			Repeat:    field("repeat"),
+			ProjectID: field("project_id"),
+			CreatedAt: field("created_at"),
+		}
+		if tags := field("tags"); tags != "" {
+			task.Tags = strings.Split(tags, ",")
+		}
+		if priority := field("priority"); priority != "" {
+			task.Priority, err = strconv.Atoi(priority)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid priority %q", line, priority)
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
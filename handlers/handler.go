@@ -1,13 +1,20 @@
 package handlers
 
-import "database/sql"
+import "go_final_project/db"
 
 // Handler - структура для хранения зависимостей обработчиков
 type Handler struct {
-	DB *sql.DB
+	DB          *db.DB
+	Attachments AttachmentConfig
+	// RequireIfMatch запрещает изменение задач без заголовка If-Match
+	RequireIfMatch bool
+	// AdminToken открывает доступ к административному API
+	AdminToken string
+	// CalendarToken открывает доступ к подписке на календарь
+	CalendarToken string
 }
 
 // NewHandler создаёт новый экземпляр Handler
-func NewHandler(db *sql.DB) *Handler {
-	return &Handler{DB: db}
+func NewHandler(conn *db.DB) *Handler {
+	return &Handler{DB: conn, Attachments: DefaultAttachmentConfig(), RequireIfMatch: true}
 }
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Языки сообщений об ошибках
+const (
+	LangRU = "ru"
+	LangEN = "en"
+)
+
+// langCookie - cookie с языком, выбранным пользователем; важнее заголовка Accept-Language
+const langCookie = "lang"
+
+// catalogs - каталоги сообщений об ошибках по языкам
+var catalogs = map[string]map[string]string{
+	LangRU: messagesRU,
+	LangEN: messagesEN,
+}
+
+// defaultLanguage - язык сообщений, если клиент не выбрал поддерживаемый язык
+var defaultLanguage = LangRU
+
+// SetDefaultLanguage задаёт язык сообщений по умолчанию (TODO_LANG).
+// Пустое значение оставляет русский язык.
+func SetDefaultLanguage(lang string) error {
+	if lang == "" {
+		return nil
+	}
+	lang = strings.ToLower(lang)
+	if _, ok := catalogs[lang]; !ok {
+		return fmt.Errorf("unsupported language %q (expected %s or %s)", lang, LangRU, LangEN)
+	}
+	defaultLanguage = lang
+	return nil
+}
+
+// requestLanguage выбирает язык сообщений для запроса: сначала настройка
+// пользователя (cookie lang), затем заголовок Accept-Language, затем язык по умолчанию
+func requestLanguage(r *http.Request) string {
+	if cookie, err := r.Cookie(langCookie); err == nil {
+		if lang := strings.ToLower(cookie.Value); catalogs[lang] != nil {
+			return lang
+		}
+	}
+	if lang := acceptLanguage(r.Header.Get("Accept-Language")); lang != "" {
+		return lang
+	}
+	return defaultLanguage
+}
+
+// acceptLanguage возвращает поддерживаемый язык с наибольшим весом из заголовка
+// Accept-Language (например, "en-US,en;q=0.9,ru;q=0.8") или пустую строку
+func acceptLanguage(header string) string {
+	type option struct {
+		lang    string
+		quality float64
+	}
+	var options []option
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		// Язык определяется по основной части тега: en-US - английский
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if catalogs[lang] != nil && quality > 0 {
+			options = append(options, option{lang, quality})
+		}
+	}
+	if len(options) == 0 {
+		return ""
+	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].quality > options[j].quality })
+	return options[0].lang
+}
+
+// message возвращает текст сообщения с кодом code на языке lang.
+// Если сообщения нет в каталоге языка, берётся сообщение на языке по умолчанию.
+func message(lang, code string, args ...any) string {
+	text, ok := catalogs[lang][code]
+	if !ok {
+		if text, ok = catalogs[defaultLanguage][code]; !ok {
+			text = code
+		}
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
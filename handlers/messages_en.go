@@ -0,0 +1,114 @@
+package handlers
+
+// messagesEN - сообщения об ошибках на английском языке по кодам ошибок
+var messagesEN = map[string]string{
+	"invalid_json":               "Invalid JSON",
+	"method_not_allowed":         "Method not allowed",
+	"unsupported_media_type":     "Unsupported content type (expected %s)",
+	"response_encoding_failed":   "Failed to encode response",
+	"internal_error":             "Internal server error",
+	"service_unavailable":        "Database is not responding, retry later",
+	"task_id_required":           "Task id is required",
+	"task_id_invalid":            "Task id must be a number",
+	"task_id_immutable":          "Task id cannot be changed",
+	"task_not_found":             "Task not found",
+	"invalid_date":               "Invalid date (expected YYYYMMDD)",
+	"invalid_repeat":             "Invalid repeat rule",
+	"title_required":             "Task title is required",
+	"invalid_comment":            "Comment must be a string",
+	"invalid_tags":               "Invalid tag list",
+	"invalid_priority":           "Invalid task priority",
+	"field_read_only":            "Field %s is read-only",
+	"unknown_field":              "Unknown field %s",
+	"invalid_now":                "Invalid now parameter (expected YYYYMMDD)",
+	"invalid_created_at":         "Invalid created_at (expected RFC 3339 time)",
+	"task_conflict":              "Task was modified by a concurrent request",
+	"precondition_failed":        "Task was modified by another client, reload it",
+	"precondition_required":      "If-Match header with the task version is required",
+	"version_required":           "Task version is required",
+	"task_create_failed":         "Failed to create task",
+	"task_update_failed":         "Failed to update task",
+	"task_delete_failed":         "Failed to delete task",
+	"task_done_failed":           "Failed to complete task",
+	"stored_repeat_invalid":      "The task's stored repeat rule is invalid, fix it before completing the task",
+	"task_move_failed":           "Failed to move task",
+	"invalid_tag_filter":         "Invalid tag filter",
+	"invalid_project_filter":     "Invalid project_id (expected a project id or none)",
+	"invalid_sort":               "Invalid sort parameter",
+	"invalid_date_filter":        "Invalid %s date (expected YYYYMMDD)",
+	"invalid_date_range":         "Invalid date range: from is after to",
+	"invalid_overdue":            "Invalid overdue (expected 1 or 0)",
+	"invalid_repeat_filter":      "Invalid repeat filter (expected none, any, d or y)",
+	"invalid_has_comment":        "Invalid has_comment (expected 1 or 0)",
+	"invalid_tag_mode":           "Invalid tag_mode (expected and or or)",
+	"invalid_cursor":             "Invalid cursor",
+	"cursor_requires_date_sort":  "Cursor pagination requires sorting by date",
+	"task_list_failed":           "Failed to retrieve tasks",
+	"project_id_required":        "Project id is required",
+	"project_id_invalid":         "Project id must be a number",
+	"project_not_found":          "Project not found",
+	"unknown_project":            "Project not found",
+	"project_archived":           "Cannot add tasks to an archived project",
+	"project_name_required":      "Project name is required",
+	"invalid_color":              "Invalid color (expected #RRGGBB)",
+	"invalid_delete_mode":        "Unknown delete mode (expected detach or cascade)",
+	"project_list_failed":        "Failed to retrieve projects",
+	"project_create_failed":      "Failed to create project",
+	"project_update_failed":      "Failed to update project",
+	"project_delete_failed":      "Failed to delete project",
+	"subtask_id_invalid":         "Subtask id must be a number",
+	"subtask_title_required":     "Subtask title is required",
+	"subtask_not_found":          "Subtask not found",
+	"invalid_subtask_order":      "The list must contain all subtasks of the task",
+	"subtask_list_failed":        "Failed to retrieve subtasks",
+	"subtask_create_failed":      "Failed to create subtask",
+	"subtask_update_failed":      "Failed to update subtask",
+	"subtask_reorder_failed":     "Failed to reorder subtasks",
+	"subtask_delete_failed":      "Failed to delete subtask",
+	"attachment_id_invalid":      "Attachment id must be a number",
+	"attachment_not_found":       "Attachment not found",
+	"file_required":              "Failed to read the file (field file, at most %d bytes)",
+	"file_unreadable":            "Failed to read the file",
+	"file_too_large":             "File exceeds the size limit",
+	"file_save_failed":           "Failed to save the file",
+	"attachment_list_failed":     "Failed to retrieve attachments",
+	"attachment_create_failed":   "Failed to add attachment",
+	"attachment_read_failed":     "Failed to read attachment",
+	"attachment_delete_failed":   "Failed to delete attachment",
+	"audit_id_required":          "Task id or audit record id is required",
+	"audit_id_invalid":           "Audit record id must be a number",
+	"audit_record_not_found":     "Audit record not found",
+	"audit_record_mismatch":      "Audit record belongs to another task",
+	"audit_record_empty":         "Audit record has no task state",
+	"audit_record_corrupt":       "Audit record is corrupted",
+	"history_failed":             "Failed to retrieve the change history",
+	"restore_failed":             "Failed to restore task",
+	"invalid_batch_mode":         "Unknown mode (expected atomic or best_effort)",
+	"batch_empty":                "No operations given",
+	"batch_too_large":            "Too many operations (at most %d)",
+	"batch_invalid":              "The request contains errors, no operations were applied",
+	"batch_aborted":              "An operation failed, all changes were rolled back",
+	"batch_task_required":        "Task is required",
+	"unknown_batch_op":           "Unknown operation (expected create, update, delete or done)",
+	"batch_failed":               "Failed to apply the operation",
+	"invalid_format":             "Unknown format (expected json or csv)",
+	"invalid_import_mode":        "Unknown import mode (expected merge or replace)",
+	"import_parse_failed":        "Failed to parse the import file",
+	"duplicate_task_id":          "Duplicate task id",
+	"import_invalid":             "The file contains errors, no tasks were imported",
+	"import_failed":              "Failed to import tasks",
+	"invalid_calendar_component": "Unknown calendar component (expected vevent or vtodo)",
+	"calendar_parse_failed":      "Failed to parse the calendar",
+	"calendar_feed_disabled":     "Calendar subscription is disabled",
+	"calendar_not_found":         "Calendar not found",
+	"invalid_calendar_date":      "Invalid date: %s",
+	"calendar_entry_closed":      "Entry is completed or cancelled",
+	"repeat_not_converted":       "Repeat rule %s is not converted",
+	"first_project_only":         "The task belongs to the first project only",
+	"entry_parse_failed":         "Failed to parse the line",
+	"admin_disabled":             "Admin API is disabled",
+	"admin_unauthorized":         "Invalid admin token",
+	"reencrypt_failed":           "Failed to re-encrypt data",
+	"backup_failed":              "Failed to create a backup",
+	"backup_read_failed":         "Failed to read the backup",
+}
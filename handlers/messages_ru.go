@@ -0,0 +1,114 @@
+package handlers
+
+// messagesRU - сообщения об ошибках на русском языке по кодам ошибок
+var messagesRU = map[string]string{
+	"invalid_json":               "Неверный формат JSON",
+	"method_not_allowed":         "Метод не поддерживается",
+	"unsupported_media_type":     "Неподдерживаемый тип содержимого (ожидается %s)",
+	"response_encoding_failed":   "Ошибка при формировании ответа",
+	"internal_error":             "Внутренняя ошибка сервера",
+	"service_unavailable":        "База данных не отвечает, повторите запрос позже",
+	"task_id_required":           "Не указан идентификатор задачи",
+	"task_id_invalid":            "Идентификатор задачи должен быть числом",
+	"task_id_immutable":          "Идентификатор задачи нельзя изменить",
+	"task_not_found":             "Задача не найдена",
+	"invalid_date":               "Неверный формат даты (ожидается YYYYMMDD)",
+	"invalid_repeat":             "Некорректное правило повторения",
+	"title_required":             "Не указан заголовок задачи",
+	"invalid_comment":            "Комментарий должен быть строкой",
+	"invalid_tags":               "Некорректный список тегов",
+	"invalid_priority":           "Некорректный приоритет задачи",
+	"field_read_only":            "Поле %s доступно только для чтения",
+	"unknown_field":              "Неизвестное поле %s",
+	"invalid_now":                "Неверный параметр now (ожидается YYYYMMDD)",
+	"invalid_created_at":         "Неверная дата создания created_at (ожидается время в формате RFC 3339)",
+	"task_conflict":              "Задача уже изменена параллельным запросом",
+	"precondition_failed":        "Задача была изменена другим клиентом, обновите данные",
+	"precondition_required":      "Не указан заголовок If-Match с версией задачи",
+	"version_required":           "Не указана версия задачи",
+	"task_create_failed":         "Не удалось добавить задачу",
+	"task_update_failed":         "Не удалось обновить задачу",
+	"task_delete_failed":         "Не удалось удалить задачу",
+	"task_done_failed":           "Не удалось завершить задачу",
+	"stored_repeat_invalid":      "Сохранённое правило повторения задачи некорректно, исправьте его перед завершением",
+	"task_move_failed":           "Не удалось перенести задачу",
+	"invalid_tag_filter":         "Некорректный фильтр по тегам",
+	"invalid_project_filter":     "Некорректный фильтр по проекту (ожидается номер проекта или none)",
+	"invalid_sort":               "Неизвестный порядок сортировки",
+	"invalid_date_filter":        "Неверный формат даты %s (ожидается YYYYMMDD)",
+	"invalid_date_range":         "Неверный диапазон дат: from позже to",
+	"invalid_overdue":            "Неверный параметр overdue (ожидается 1 или 0)",
+	"invalid_repeat_filter":      "Неверный фильтр по повторению (ожидается none, any, d или y)",
+	"invalid_has_comment":        "Неверный параметр has_comment (ожидается 1 или 0)",
+	"invalid_tag_mode":           "Неверный параметр tag_mode (ожидается and или or)",
+	"invalid_cursor":             "Некорректный курсор",
+	"cursor_requires_date_sort":  "Постраничный вывод по курсору возможен только при сортировке по дате",
+	"task_list_failed":           "Не удалось получить список задач",
+	"project_id_required":        "Не указан идентификатор проекта",
+	"project_id_invalid":         "Идентификатор проекта должен быть числом",
+	"project_not_found":          "Проект не найден",
+	"unknown_project":            "Проект не найден",
+	"project_archived":           "Нельзя добавлять задачи в архивный проект",
+	"project_name_required":      "Не указано название проекта",
+	"invalid_color":              "Неверный формат цвета (ожидается #RRGGBB)",
+	"invalid_delete_mode":        "Неизвестный режим удаления (ожидается detach или cascade)",
+	"project_list_failed":        "Не удалось получить список проектов",
+	"project_create_failed":      "Не удалось добавить проект",
+	"project_update_failed":      "Не удалось обновить проект",
+	"project_delete_failed":      "Не удалось удалить проект",
+	"subtask_id_invalid":         "Идентификатор подзадачи должен быть числом",
+	"subtask_title_required":     "Не указан заголовок подзадачи",
+	"subtask_not_found":          "Подзадача не найдена",
+	"invalid_subtask_order":      "Список должен содержать все подзадачи задачи",
+	"subtask_list_failed":        "Не удалось получить подзадачи",
+	"subtask_create_failed":      "Не удалось добавить подзадачу",
+	"subtask_update_failed":      "Не удалось изменить подзадачу",
+	"subtask_reorder_failed":     "Не удалось изменить порядок подзадач",
+	"subtask_delete_failed":      "Не удалось удалить подзадачу",
+	"attachment_id_invalid":      "Идентификатор вложения должен быть числом",
+	"attachment_not_found":       "Вложение не найдено",
+	"file_required":              "Не удалось прочитать файл (поле file, не более %d байт)",
+	"file_unreadable":            "Не удалось прочитать файл",
+	"file_too_large":             "Файл превышает допустимый размер",
+	"file_save_failed":           "Не удалось сохранить файл",
+	"attachment_list_failed":     "Не удалось получить список вложений",
+	"attachment_create_failed":   "Не удалось добавить вложение",
+	"attachment_read_failed":     "Не удалось прочитать вложение",
+	"attachment_delete_failed":   "Не удалось удалить вложение",
+	"audit_id_required":          "Не указан идентификатор задачи или записи журнала",
+	"audit_id_invalid":           "Идентификатор записи журнала должен быть числом",
+	"audit_record_not_found":     "Запись журнала не найдена",
+	"audit_record_mismatch":      "Запись журнала относится к другой задаче",
+	"audit_record_empty":         "Запись журнала не содержит состояния задачи",
+	"audit_record_corrupt":       "Повреждённая запись журнала",
+	"history_failed":             "Не удалось получить журнал изменений",
+	"restore_failed":             "Не удалось восстановить задачу",
+	"invalid_batch_mode":         "Неизвестный режим (ожидается atomic или best_effort)",
+	"batch_empty":                "Не указаны операции",
+	"batch_too_large":            "Слишком много операций (не более %d)",
+	"batch_invalid":              "Запрос содержит ошибки, операции не выполнены",
+	"batch_aborted":              "Операция не выполнена, изменения отменены",
+	"batch_task_required":        "Не указана задача",
+	"unknown_batch_op":           "Неизвестная операция (ожидается create, update, delete или done)",
+	"batch_failed":               "Не удалось выполнить операцию",
+	"invalid_format":             "Неизвестный формат (ожидается json или csv)",
+	"invalid_import_mode":        "Неизвестный режим импорта (ожидается merge или replace)",
+	"import_parse_failed":        "Не удалось разобрать файл импорта",
+	"duplicate_task_id":          "Повторяющийся идентификатор задачи",
+	"import_invalid":             "Файл содержит ошибки, задачи не импортированы",
+	"import_failed":              "Не удалось импортировать задачи",
+	"invalid_calendar_component": "Неизвестный тип записей календаря (ожидается vevent или vtodo)",
+	"calendar_parse_failed":      "Не удалось разобрать календарь",
+	"calendar_feed_disabled":     "Подписка на календарь отключена",
+	"calendar_not_found":         "Календарь не найден",
+	"invalid_calendar_date":      "Неверный формат даты: %s",
+	"calendar_entry_closed":      "Запись выполнена или отменена",
+	"repeat_not_converted":       "Правило повторения %s не переведено",
+	"first_project_only":         "Задача относится только к первому проекту",
+	"entry_parse_failed":         "Не удалось разобрать строку",
+	"admin_disabled":             "Административный API отключён",
+	"admin_unauthorized":         "Неверный токен администратора",
+	"reencrypt_failed":           "Не удалось перешифровать данные",
+	"backup_failed":              "Не удалось создать резервную копию",
+	"backup_read_failed":         "Не удалось прочитать резервную копию",
+}
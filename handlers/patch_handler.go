@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go_final_project/constants"
+	"go_final_project/db"
+	"go_final_project/models"
+	"go_final_project/utils"
+)
+
+// mergePatchType - тип содержимого JSON Merge Patch (RFC 7396)
+const mergePatchType = "application/merge-patch+json"
+
+// jsonNull - значение null в JSON Merge Patch: поле сбрасывается к пустому значению
+var jsonNull = []byte("null")
+
+// patchTask частично обновляет задачу по правилам JSON Merge Patch:
+// переданные поля заменяются, null сбрасывает поле, отсутствующие поля не меняются.
+// Проверяются только переданные поля. Идентификатор задачи берётся из пути, ?id= или из тела.
+func (h *Handler) patchTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergePatchType && mediaType != "application/json") {
+			writeError(w, r, errUnsupportedMediaType.with(mergePatchType))
+			return
+		}
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+
+	id := taskIDParam(r)
+	if raw, ok := patch["id"]; ok {
+		var bodyID string
+		if err := json.Unmarshal(raw, &bodyID); err != nil || (id != "" && bodyID != id) {
+			writeError(w, r, errTaskIDImmutable)
+			return
+		}
+		id = bodyID
+		delete(patch, "id")
+	}
+	if id == "" {
+		writeError(w, r, errTaskIDRequired)
+		return
+	}
+	taskID, err := strconv.Atoi(id)
+	if err != nil {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+
+	before, err := db.GetTaskByID(r.Context(), h.DB, taskID)
+	if err != nil {
+		writeDBError(w, r, err, errTaskNotFound)
+		return
+	}
+
+	task := *before
+	if e := applyTaskPatch(&task, patch); e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	// Правило повторения проверяется, если изменилось оно само или дата, от которой оно считается
+	_, repeatChanged := patch["repeat"]
+	_, dateChanged := patch["date"]
+	if task.Repeat != "" && (repeatChanged || dateChanged) {
+		if _, err := utils.NextDate(utils.NormalizeDate(time.Now()), task.Date, task.Repeat); err != nil {
+			writeError(w, r, errRepeatInvalid)
+			return
+		}
+	}
+
+	if task.ProjectID != before.ProjectID && !h.checkTaskProject(w, r, task.ProjectID) {
+		return
+	}
+
+	var ok bool
+	if task.Version, ok = h.checkIfMatch(w, r, before.Version); !ok {
+		return
+	}
+
+	_, tagsChanged := patch["tags"]
+	op := db.BatchOp{Action: db.BatchUpdate, Task: task, SetTags: tagsChanged}
+	task.Version = before.Version + 1
+	if _, err := h.execTaskOp(r, op, models.AuditUpdate, before, &task); err != nil {
+		switch {
+		case errors.Is(err, db.ErrTaskConflict) && op.Task.Version != 0:
+			// Задача изменилась между проверкой версии и обновлением
+			writeError(w, r, errPreconditionFailed)
+		case errors.Is(err, db.ErrTaskConflict):
+			// Без условия на версию обновление не находит только удалённую задачу
+			writeError(w, r, errTaskNotFound)
+		default:
+			writeDBError(w, r, err, errTaskUpdateFailed)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(task.Version))
+	if err := json.NewEncoder(w).Encode(task); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// applyTaskPatch применяет поля патча к задаче и возвращает ошибку,
+// если поле неизвестно, доступно только для чтения или имеет неверное значение
+func applyTaskPatch(task *models.Task, patch map[string]json.RawMessage) *apiError {
+	for field, raw := range patch {
+		null := bytes.Equal(bytes.TrimSpace(raw), jsonNull)
+
+		switch field {
+		case "date":
+			if null || json.Unmarshal(raw, &task.Date) != nil {
+				return errDateInvalid
+			}
+			if _, err := time.Parse(constants.DateFormat, task.Date); err != nil {
+				return errDateInvalid
+			}
+		case "title":
+			if null || json.Unmarshal(raw, &task.Title) != nil || task.Title == "" {
+				return errTitleRequired
+			}
+		case "comment":
+			task.Comment = ""
+			if !null && json.Unmarshal(raw, &task.Comment) != nil {
+				return errCommentInvalid
+			}
+		case "repeat":
+			task.Repeat = ""
+			if !null && json.Unmarshal(raw, &task.Repeat) != nil {
+				return errRepeatInvalid
+			}
+		case "tags":
+			// null очищает теги так же, как пустой массив
+			tags := []string{}
+			if !null && json.Unmarshal(raw, &tags) != nil {
+				return errTagsInvalid
+			}
+			var err error
+			if task.Tags, err = utils.NormalizeTags(tags); err != nil {
+				return errTagsInvalid
+			}
+		case "project_id":
+			task.ProjectID = ""
+			if !null && json.Unmarshal(raw, &task.ProjectID) != nil {
+				return errProjectIDInvalid
+			}
+		case "priority":
+			task.Priority = 0
+			if !null && json.Unmarshal(raw, &task.Priority) != nil {
+				return errPriorityInvalid
+			}
+			if task.Priority < 0 || task.Priority > constants.MaxPriority {
+				return errPriorityInvalid
+			}
+		case "created_at", "checklist":
+			return errFieldReadOnly.with(field)
+		default:
+			return errFieldUnknown.with(field)
+		}
+	}
+	return nil
+}
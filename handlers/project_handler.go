@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"go_final_project/db"
+	"go_final_project/models"
+)
+
+// colorPattern - допустимый формат цвета проекта (#RRGGBB)
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ProjectListResponse структура ответа со списком проектов
+type ProjectListResponse struct {
+	Projects []models.Project `json:"projects"`
+}
+
+// HandleProject обрабатывает запросы API для проектов
+func (h *Handler) HandleProject(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.addProject(w, r)
+	case http.MethodGet:
+		h.getProject(w, r)
+	case http.MethodPut:
+		h.editProject(w, r)
+	case http.MethodDelete:
+		h.deleteProject(w, r)
+	default:
+		writeMethodNotAllowed(w, r, http.MethodPost, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// HandleProjectList возвращает список проектов.
+// Архивные проекты включаются при параметре archived=1.
+func (h *Handler) HandleProjectList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	projects, err := db.ListProjects(r.Context(), h.DB, r.URL.Query().Get("archived") == "1")
+	if err != nil {
+		writeDBError(w, r, err, errProjectListFailed)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(ProjectListResponse{Projects: projects}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// addProject создаёт проект
+func (h *Handler) addProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var project models.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+
+	if e := validateProject(project); e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	id, err := db.AddProject(r.Context(), h.DB, project)
+	if err != nil {
+		writeDBError(w, r, err, errProjectCreateFailed)
+		return
+	}
+
+	response := map[string]any{"id": strconv.FormatInt(id, 10)}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// getProject возвращает проект по идентификатору
+func (h *Handler) getProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	projectID, ok := parseProjectID(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	project, err := db.GetProjectByID(r.Context(), h.DB, projectID)
+	if err != nil {
+		writeDBError(w, r, err, errProjectNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(project); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// editProject обновляет проект, в том числе переводит его в архив и обратно.
+// Задачи архивного проекта сохраняются, но не показываются в общем списке.
+func (h *Handler) editProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var project models.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+
+	if _, ok := parseProjectID(w, r, project.ID); !ok {
+		return
+	}
+
+	if e := validateProject(project); e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	rowsAffected, err := db.UpdateProject(r.Context(), h.DB, project)
+	if err != nil {
+		writeDBError(w, r, err, errProjectUpdateFailed)
+		return
+	}
+	if rowsAffected == 0 {
+		writeError(w, r, errProjectNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// deleteProject удаляет проект.
+// По умолчанию задачи проекта остаются без проекта, при mode=cascade удаляются.
+func (h *Handler) deleteProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	projectID, ok := parseProjectID(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	var cascade bool
+	switch r.URL.Query().Get("mode") {
+	case "", "detach":
+	case "cascade":
+		cascade = true
+	default:
+		writeError(w, r, errProjectDeleteMode)
+		return
+	}
+
+	// Удалённые задачи записываются в журнал в транзакции удаления проекта
+	audit, err := newAuditRecord(actorFromRequest(r), models.AuditDelete, "", nil, nil)
+	if err != nil {
+		writeError(w, r, errInternal)
+		return
+	}
+	rowsAffected, paths, err := db.DeleteProject(r.Context(), h.DB, projectID, cascade, audit)
+	if err != nil {
+		writeDBError(w, r, err, errProjectDeleteFailed)
+		return
+	}
+	if rowsAffected == 0 {
+		writeError(w, r, errProjectNotFound)
+		return
+	}
+	removeAttachmentFiles(paths)
+
+	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// HandleTaskMove переносит задачу в другой проект.
+// Пустой project_id убирает задачу из проекта.
+func (h *Handler) HandleTaskMove(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		writeError(w, r, errTaskIDRequired)
+		return
+	}
+
+	taskID, err := strconv.Atoi(id)
+	if err != nil {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+
+	projectID := r.URL.Query().Get("project_id")
+	if !h.checkTaskProject(w, r, projectID) {
+		return
+	}
+
+	before, err := db.GetTaskByID(r.Context(), h.DB, taskID)
+	if err != nil {
+		writeDBError(w, r, err, errTaskNotFound)
+		return
+	}
+
+	version, ok := h.checkIfMatch(w, r, before.Version)
+	if !ok {
+		return
+	}
+
+	// Перенос сохраняется вместе с записью журнала и только если задачу
+	// не изменили после чтения: иначе прежние поля затёрли бы чужое изменение
+	op := db.BatchOp{Action: db.BatchUpdate, Task: *before}
+	op.Task.ProjectID = projectID
+	after := op.Task
+	after.Version = before.Version + 1
+	if _, err := h.execTaskOp(r, op, models.AuditUpdate, before, &after); err != nil {
+		switch {
+		case errors.Is(err, db.ErrTaskConflict) && version != 0:
+			writeError(w, r, errPreconditionFailed)
+		case errors.Is(err, db.ErrTaskConflict):
+			writeError(w, r, errTaskConflict)
+		default:
+			writeDBError(w, r, err, errTaskMoveFailed)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(after.Version))
+
+	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// checkTaskProject проверяет, что в проект можно добавить задачу.
+// При ошибке отправляет ответ клиенту и возвращает false.
+func (h *Handler) checkTaskProject(w http.ResponseWriter, r *http.Request, projectID string) bool {
+	if e := h.projectError(r.Context(), projectID); e != nil {
+		writeError(w, r, e)
+		return false
+	}
+	return true
+}
+
+// projectError проверяет, что в проект можно добавить задачу, и возвращает ошибку.
+// Пустой projectID означает задачу без проекта.
+func (h *Handler) projectError(ctx context.Context, projectID string) *apiError {
+	if projectID == "" {
+		return nil
+	}
+
+	id, err := strconv.Atoi(projectID)
+	if err != nil {
+		return errProjectIDInvalid
+	}
+
+	project, err := db.GetProjectByID(ctx, h.DB, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return errProjectUnknown
+	}
+	if err != nil {
+		return dbError(err, errInternal)
+	}
+	if project.Archived {
+		return errProjectArchived
+	}
+	return nil
+}
+
+// parseProjectID разбирает идентификатор проекта.
+// При ошибке отправляет ответ клиенту и возвращает false.
+func parseProjectID(w http.ResponseWriter, r *http.Request, id string) (int, bool) {
+	if id == "" {
+		writeError(w, r, errProjectIDRequired)
+		return 0, false
+	}
+
+	projectID, err := strconv.Atoi(id)
+	if err != nil {
+		writeError(w, r, errProjectIDInvalid)
+		return 0, false
+	}
+	return projectID, true
+}
+
+// validateProject проверяет поля проекта и возвращает ошибку
+func validateProject(project models.Project) *apiError {
+	if project.Name == "" {
+		return errProjectNameRequired
+	}
+	if project.Color != "" && !colorPattern.MatchString(project.Color) {
+		return errProjectColorInvalid
+	}
+	return nil
+}
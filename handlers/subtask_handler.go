@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go_final_project/db"
+	"go_final_project/models"
+)
+
+// SubtaskListResponse структура ответа со списком подзадач
+type SubtaskListResponse struct {
+	Subtasks []models.Subtask `json:"subtasks"`
+}
+
+// reorderRequest - новый порядок подзадач задачи
+type reorderRequest struct {
+	TaskID string   `json:"task_id"`
+	IDs    []string `json:"ids"`
+}
+
+// HandleSubtask обрабатывает запросы API для подзадач
+func (h *Handler) HandleSubtask(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.addSubtask(w, r)
+	case http.MethodDelete:
+		h.deleteSubtask(w, r)
+	default:
+		writeMethodNotAllowed(w, r, http.MethodPost, http.MethodDelete)
+	}
+}
+
+// HandleSubtaskList возвращает подзадачи задачи в порядке позиций
+func (h *Handler) HandleSubtaskList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	taskID, err := strconv.Atoi(r.URL.Query().Get("task_id"))
+	if err != nil {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+
+	subtasks, err := db.ListSubtasks(r.Context(), h.DB, taskID)
+	if err != nil {
+		writeDBError(w, r, err, errSubtaskListFailed)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(SubtaskListResponse{Subtasks: subtasks}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// addSubtask добавляет подзадачу в конец чек-листа
+func (h *Handler) addSubtask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	var subtask models.Subtask
+	if err := json.NewDecoder(r.Body).Decode(&subtask); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+
+	taskID, err := strconv.Atoi(subtask.TaskID)
+	if err != nil {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+
+	if subtask.Title == "" {
+		writeError(w, r, errSubtaskTitleRequired)
+		return
+	}
+
+	if _, err := db.GetTaskByID(r.Context(), h.DB, taskID); err != nil {
+		writeDBError(w, r, err, errTaskNotFound)
+		return
+	}
+
+	id, err := db.AddSubtask(r.Context(), h.DB, taskID, subtask.Title)
+	if err != nil {
+		writeDBError(w, r, err, errSubtaskCreateFailed)
+		return
+	}
+
+	response := map[string]any{"id": strconv.FormatInt(id, 10)}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// HandleSubtaskToggle отмечает подзадачу выполненной или снимает отметку
+func (h *Handler) HandleSubtaskToggle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		writeError(w, r, errSubtaskIDInvalid)
+		return
+	}
+
+	rowsAffected, err := db.ToggleSubtask(r.Context(), h.DB, id)
+	if err != nil {
+		writeDBError(w, r, err, errSubtaskUpdateFailed)
+		return
+	}
+	if rowsAffected == 0 {
+		writeError(w, r, errSubtaskNotFound)
+		return
+	}
+
+	subtask, err := db.GetSubtaskByID(r.Context(), h.DB, id)
+	if err != nil {
+		writeDBError(w, r, err, errSubtaskNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(subtask); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// HandleSubtaskReorder задаёт новый порядок подзадач
+func (h *Handler) HandleSubtaskReorder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	var req reorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+
+	taskID, err := strconv.Atoi(req.TaskID)
+	if err != nil {
+		writeError(w, r, errTaskIDInvalid)
+		return
+	}
+
+	ids := make([]int, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		subtaskID, err := strconv.Atoi(id)
+		if err != nil {
+			writeError(w, r, errSubtaskIDInvalid)
+			return
+		}
+		ids = append(ids, subtaskID)
+	}
+
+	if err := db.ReorderSubtasks(r.Context(), h.DB, taskID, ids); err != nil {
+		if errors.Is(err, db.ErrSubtaskSetMismatch) {
+			writeError(w, r, errSubtaskOrderInvalid)
+		} else {
+			writeDBError(w, r, err, errSubtaskReorderFailed)
+		}
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// deleteSubtask удаляет подзадачу по идентификатору
+func (h *Handler) deleteSubtask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	id, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		writeError(w, r, errSubtaskIDInvalid)
+		return
+	}
+
+	rowsAffected, err := db.DeleteSubtask(r.Context(), h.DB, id)
+	if err != nil {
+		writeDBError(w, r, err, errSubtaskDeleteFailed)
+		return
+	}
+	if rowsAffected == 0 {
+		writeError(w, r, errSubtaskNotFound)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
@@ -1,8 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -22,10 +23,12 @@ func (h *Handler) HandleTask(w http.ResponseWriter, r *http.Request) {
 		h.getTask(w, r)
 	case http.MethodPut:
 		h.editTask(w, r)
+	case http.MethodPatch:
+		h.patchTask(w, r)
 	case http.MethodDelete:
 		h.deleteTask(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodPost, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
 	}
 }
 
@@ -33,13 +36,71 @@ func (h *Handler) HandleTask(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) addTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
+	task, ok := h.createTask(w, r)
+	if !ok {
+		return
+	}
+
+	response := map[string]any{"id": task.ID}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// createTask читает задачу из тела запроса, проверяет и сохраняет её.
+// При ошибке отправляет ответ клиенту и возвращает false.
+func (h *Handler) createTask(w http.ResponseWriter, r *http.Request) (models.Task, bool) {
 	var task models.Task
 	err := json.NewDecoder(r.Body).Decode(&task)
 	if err != nil {
-		writeError(w, "Неверный формат JSON")
-		return
+		writeError(w, r, errInvalidJSON)
+		return task, false
+	}
+
+	if e := h.prepareNewTask(r.Context(), &task); e != nil {
+		writeError(w, r, e)
+		return task, false
 	}
 
+	op := db.BatchOp{Action: db.BatchCreate, Task: task, SetTags: len(task.Tags) > 0}
+	result, err := h.execTaskOp(r, op, models.AuditCreate, nil, nil)
+	if err != nil {
+		writeDBError(w, r, err, errTaskCreateFailed)
+		return task, false
+	}
+	task.ID = strconv.FormatInt(result.ID, 10)
+	task.Version = 1 // версия новой задачи в базе
+	return task, true
+}
+
+// execTaskOp выполняет операцию с задачей вместе с записью журнала action
+// в одной транзакции: изменение не сохранится без записи журнала, а клиент
+// не получит ошибку для уже сохранённого изменения.
+// Снимки задачи до и после изменения уточняются в db.ExecTaskOp по состоянию,
+// прочитанному в транзакции.
+func (h *Handler) execTaskOp(r *http.Request, op db.BatchOp, action string, before, after *models.Task) (db.BatchResult, error) {
+	rec, err := newAuditRecord(actorFromRequest(r), action, op.Task.ID, before, after)
+	if err != nil {
+		return db.BatchResult{}, err
+	}
+	op.Audit = &rec
+	return db.ExecTaskOp(r.Context(), h.DB, op, utils.NormalizeDate(time.Now()))
+}
+
+// taskIDParam возвращает идентификатор задачи из пути (/api/v2/tasks/{id})
+// или из параметра ?id= запросов первой версии API
+func taskIDParam(r *http.Request) string {
+	if id := r.PathValue("id"); id != "" {
+		return id
+	}
+	return r.URL.Query().Get("id")
+}
+
+// prepareNewTask проверяет новую задачу и приводит её поля к сохраняемому виду:
+// подставляет дату, переносит прошедшую дату, нормализует теги.
+// Дату создания всегда задаёт сервер.
+// Возвращает ошибку или nil, если задачу можно сохранить.
+func (h *Handler) prepareNewTask(ctx context.Context, task *models.Task) *apiError {
 	now := utils.NormalizeDate(time.Now())
 
 	if task.Date == "" {
@@ -47,8 +108,7 @@ func (h *Handler) addTask(w http.ResponseWriter, r *http.Request) {
 	} else {
 		parsedDate, err := time.Parse(constants.DateFormat, task.Date)
 		if err != nil {
-			writeError(w, "Неверный формат даты (ожидается YYYYMMDD)")
-			return
+			return errDateInvalid
 		}
 
 		if parsedDate.Before(now) || parsedDate.Equal(now) {
@@ -57,54 +117,86 @@ func (h *Handler) addTask(w http.ResponseWriter, r *http.Request) {
 			} else {
 				task.Date, err = utils.NextDate(now, task.Date, task.Repeat)
 				if err != nil {
-					writeError(w, "Некорректное правило повторения")
-					return
+					return errRepeatInvalid
 				}
 			}
 		}
 	}
 
+	// Правило проверяется и для будущей даты, иначе ошибка в нём
+	// проявится только при выполнении задачи
+	if task.Repeat != "" {
+		if _, err := utils.NextDate(now, task.Date, task.Repeat); err != nil {
+			return errRepeatInvalid
+		}
+	}
+
 	if task.Title == "" {
-		writeError(w, "Не указан заголовок задачи")
-		return
+		return errTitleRequired
 	}
 
-	id, err := db.AddTask(h.DB, task.Date, task.Title, task.Comment, task.Repeat)
+	var err error
+	task.Tags, err = utils.NormalizeTags(task.Tags)
 	if err != nil {
-		writeError(w, "Не удалось добавить задачу")
-		return
+		return errTagsInvalid
 	}
 
-	response := map[string]any{"id": strconv.FormatInt(id, 10)}
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		writeError(w, "Ошибка при формировании ответа")
+	if task.Priority < 0 || task.Priority > constants.MaxPriority {
+		return errPriorityInvalid
+	}
+
+	if e := h.projectError(ctx, task.ProjectID); e != nil {
+		return e
 	}
+
+	task.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	return nil
+}
+
+// prepareImportedTask проверяет задачу из импорта так же, как новую, но сохраняет
+// переданную дату создания, если она указана в формате RFC 3339.
+func (h *Handler) prepareImportedTask(ctx context.Context, task *models.Task) *apiError {
+	createdAt := task.CreatedAt
+	if e := h.prepareNewTask(ctx, task); e != nil {
+		return e
+	}
+	if createdAt == "" {
+		return nil
+	}
+
+	created, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return errCreatedAtInvalid
+	}
+	task.CreatedAt = created.UTC().Format(time.RFC3339)
+	return nil
 }
 
 // getTask возвращает данные задачи по идентификатору
 func (h *Handler) getTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	id := r.URL.Query().Get("id")
+	id := taskIDParam(r)
 	if id == "" {
-		writeError(w, "Не указан идентификатор задачи")
+		writeError(w, r, errTaskIDRequired)
 		return
 	}
 
 	taskID, err := strconv.Atoi(id)
 	if err != nil {
-		writeError(w, "Идентификатор задачи должен быть числом")
+		writeError(w, r, errTaskIDInvalid)
 		return
 	}
 
-	task, err := db.GetTaskByID(h.DB, taskID)
+	task, err := db.GetTaskByID(r.Context(), h.DB, taskID)
 	if err != nil {
-		writeError(w, "Ошибка при получении задачи")
+		writeDBError(w, r, err, errTaskNotFound)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(task.Version))
 	if err := json.NewEncoder(w).Encode(task); err != nil {
-		writeError(w, "Ошибка при формировании ответа")
+		writeError(w, r, errEncodeResponse)
 	}
 }
 
@@ -112,91 +204,190 @@ func (h *Handler) getTask(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) editTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	var task models.Task
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		writeError(w, "Неверный формат JSON")
+	var input TaskInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, r, errInvalidJSON)
 		return
 	}
+	task := input.task()
 
-	if task.ID == "" {
-		writeError(w, "Не указан идентификатор задачи")
+	// В /api/v2/tasks/{id} идентификатор задаётся путём, в теле его можно не указывать
+	if id := r.PathValue("id"); id != "" {
+		if task.ID != "" && task.ID != id {
+			writeError(w, r, errTaskIDImmutable)
+			return
+		}
+		task.ID = id
+	}
+
+	taskID, e := validateTaskUpdate(&task)
+	if e != nil {
+		writeError(w, r, e)
+		return
+	}
+
+	// Сохраняем прежнее состояние для журнала изменений
+	before, err := db.GetTaskByID(r.Context(), h.DB, taskID)
+	if err != nil {
+		writeDBError(w, r, err, errTaskNotFound)
+		return
+	}
+
+	task.CreatedAt = before.CreatedAt
+	// Как и теги, приоритет без поля priority не меняется
+	if input.Priority == nil {
+		task.Priority = before.Priority
+	}
+
+	var ok bool
+	if task.Version, ok = h.checkIfMatch(w, r, before.Version); !ok {
 		return
 	}
 
+	// Без project_id задача остаётся в прежнем проекте; для переноса есть /api/task/move
+	if task.ProjectID == "" {
+		task.ProjectID = before.ProjectID
+	} else if task.ProjectID != before.ProjectID && !h.checkTaskProject(w, r, task.ProjectID) {
+		return
+	}
+
+	// Если теги не переданы, оставляем прежние; пустой массив очищает теги
+	op := db.BatchOp{Action: db.BatchUpdate, Task: task, SetTags: task.Tags != nil}
+	if !op.SetTags {
+		task.Tags = before.Tags
+	}
+	after := task
+	after.Version = before.Version + 1
+	if _, err := h.execTaskOp(r, op, models.AuditUpdate, before, &after); err != nil {
+		switch {
+		case errors.Is(err, db.ErrTaskConflict) && task.Version != 0:
+			// Задача изменилась между проверкой версии и обновлением
+			writeError(w, r, errPreconditionFailed)
+		case errors.Is(err, db.ErrTaskConflict):
+			// Без условия на версию обновление не находит только удалённую задачу
+			writeError(w, r, errTaskNotFound)
+		default:
+			writeDBError(w, r, err, errTaskUpdateFailed)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(before.Version+1))
+
+	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
+}
+
+// TaskInput - задача в теле запроса на создание или изменение. Priority - указатель, чтобы
+// отличить отсутствующее поле (приоритет не меняется) от явного 0.
+type TaskInput struct {
+	models.Task
+	Priority *int `json:"priority,omitempty"`
+}
+
+// task возвращает задачу из запроса с переданным приоритетом
+func (in TaskInput) task() models.Task {
+	task := in.Task
+	if in.Priority != nil {
+		task.Priority = *in.Priority
+	}
+	return task
+}
+
+// validateTaskUpdate проверяет новое состояние изменяемой задачи: подставляет
+// сегодняшнюю дату и нормализует теги. Возвращает ID задачи и ошибку
+// или nil, если изменение можно сохранить.
+func validateTaskUpdate(task *models.Task) (int, *apiError) {
+	if task.ID == "" {
+		return 0, errTaskIDRequired
+	}
+
+	taskID, err := strconv.Atoi(task.ID)
+	if err != nil {
+		return 0, errTaskIDInvalid
+	}
+
 	if task.Date != "" {
 		if _, err := time.Parse(constants.DateFormat, task.Date); err != nil {
-			writeError(w, "Неверный формат даты (ожидается YYYYMMDD)")
-			return
+			return 0, errDateInvalid
 		}
 	} else {
 		task.Date = utils.NormalizeDate(time.Now()).Format(constants.DateFormat)
 	}
 
+	if task.Repeat != "" {
+		if _, err := utils.NextDate(utils.NormalizeDate(time.Now()), task.Date, task.Repeat); err != nil {
+			return 0, errRepeatInvalid
+		}
+	}
+
 	if task.Title == "" {
-		writeError(w, "Заголовок задачи обязателен")
-		return
+		return 0, errTitleRequired
 	}
 
-	rowsAffected, err := db.UpdateTask(h.DB, task)
-	if err != nil || rowsAffected == 0 {
-		writeError(w, "Задача не найдена или не удалось обновить")
-		return
+	task.Tags, err = utils.NormalizeTags(task.Tags)
+	if err != nil {
+		return 0, errTagsInvalid
 	}
 
-	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
-		writeError(w, "Ошибка при отправке ответа")
+	if task.Priority < 0 || task.Priority > constants.MaxPriority {
+		return 0, errPriorityInvalid
 	}
+	return taskID, nil
 }
 
 // HandleTaskDone завершает задачу
 func (h *Handler) HandleTaskDone(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	id := r.URL.Query().Get("id")
+	id := taskIDParam(r)
 	if id == "" {
-		writeError(w, "Не указан идентификатор задачи")
+		writeError(w, r, errTaskIDRequired)
 		return
 	}
 
 	taskID, err := strconv.Atoi(id)
 	if err != nil {
-		writeError(w, "Идентификатор задачи должен быть числом")
+		writeError(w, r, errTaskIDInvalid)
 		return
 	}
 
 	// Получаем задачу из базы данных
-	task, err := db.GetTaskByID(h.DB, taskID)
+	task, err := db.GetTaskByID(r.Context(), h.DB, taskID)
 	if err != nil {
-		writeError(w, "Ошибка при получении задачи")
+		writeDBError(w, r, err, errTaskNotFound)
+		return
+	}
+	version, ok := h.checkIfMatch(w, r, task.Version)
+	if !ok {
 		return
 	}
 
-	if task.Repeat == "" {
-		// Если задача одноразовая, удаляем её
-		_, err = db.DeleteTask(h.DB, taskID)
-		if err != nil {
-			writeError(w, "Не удалось удалить задачу")
-			return
-		}
-	} else {
-		// Если задача повторяющаяся, обновляем дату
-		now := utils.NormalizeDate(time.Now())
-		nextDate, err := utils.NextDate(now, task.Date, task.Repeat)
-		if err != nil {
-			writeError(w, "Ошибка при расчёте следующей даты")
-			return
+	// Одноразовая задача удаляется вместе с файлами вложений,
+	// повторяющаяся переносится на следующую дату
+	result, err := h.execTaskOp(r, db.BatchOp{Action: db.BatchDone, Task: *task}, models.AuditDone, task, nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrTaskConflict) && version != 0:
+			writeError(w, r, errPreconditionFailed)
+		case errors.Is(err, db.ErrTaskConflict):
+			writeError(w, r, errTaskConflict)
+		case errors.Is(err, db.ErrStoredRepeatInvalid):
+			writeError(w, r, errStoredRepeatInvalid)
+		default:
+			writeDBError(w, r, err, errTaskDoneFailed)
 		}
+		return
+	}
 
-		task.Date = nextDate
-		_, err = db.UpdateTask(h.DB, *task)
-		if err != nil {
-			writeError(w, "Не удалось обновить задачу")
-			return
-		}
+	removeAttachmentFiles(result.Paths)
+	if result.Task != nil {
+		w.Header().Set("ETag", formatETag(result.Task.Version))
 	}
 
 	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
-		writeError(w, "Ошибка при отправке ответа")
+		writeError(w, r, errEncodeResponse)
 	}
 }
 
@@ -204,39 +395,48 @@ func (h *Handler) HandleTaskDone(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) deleteTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
-	id := r.URL.Query().Get("id")
+	id := taskIDParam(r)
 	if id == "" {
-		writeError(w, "Не указан идентификатор задачи")
+		writeError(w, r, errTaskIDRequired)
 		return
 	}
 
 	taskID, err := strconv.Atoi(id)
 	if err != nil {
-		writeError(w, "Идентификатор задачи должен быть числом")
+		writeError(w, r, errTaskIDInvalid)
 		return
 	}
 
-	// Удаляем задачу из базы данных через db.DeleteTask
-	rowsAffected, err := db.DeleteTask(h.DB, taskID)
+	// Прежнее состояние нужно для журнала и проверки версии
+	before, err := db.GetTaskByID(r.Context(), h.DB, taskID)
 	if err != nil {
-		writeError(w, "Не удалось удалить задачу")
+		writeDBError(w, r, err, errTaskNotFound)
 		return
 	}
 
-	// Проверяем, была ли удалена задача
-	if rowsAffected == 0 {
-		writeError(w, "Задача не найдена")
+	version, ok := h.checkIfMatch(w, r, before.Version)
+	if !ok {
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
-		writeError(w, "Ошибка при отправке ответа")
+	// Задача удаляется только при совпадении версии (если она указана)
+	op := db.BatchOp{Action: db.BatchDelete, Task: *before}
+	op.Task.Version = version
+	result, err := h.execTaskOp(r, op, models.AuditDelete, before, nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrTaskConflict) && version != 0:
+			writeError(w, r, errPreconditionFailed)
+		case errors.Is(err, db.ErrTaskConflict):
+			writeError(w, r, errTaskNotFound)
+		default:
+			writeDBError(w, r, err, errTaskDeleteFailed)
+		}
+		return
 	}
-}
+	removeAttachmentFiles(result.Paths)
 
-// writeError отправляет сообщение об ошибке в формате JSON
-func writeError(w http.ResponseWriter, message string) {
-	log.Printf("[ERROR] %s", message)
-	w.WriteHeader(http.StatusBadRequest)
-	json.NewEncoder(w).Encode(map[string]any{"error": message})
+	if err := json.NewEncoder(w).Encode(map[string]any{}); err != nil {
+		writeError(w, r, errEncodeResponse)
+	}
 }
@@ -2,18 +2,41 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"go_final_project/constants"
+	"go_final_project/db"
 	"go_final_project/models"
+	"go_final_project/utils"
 )
 
 // Константа для лимита задач
 const DefaultTaskLimit = 50
 
+// searchDateFormat - формат даты в строке поиска (dd.mm.yyyy)
+const searchDateFormat = "02.01.2006"
+
+// TaskListItem - задача в списке вместе с её версией. Клиент передаёт версию
+// в If-Match при изменении задачи из списка, не запрашивая задачу отдельно.
+// Версия передаётся строкой, как и остальные поля задачи.
+type TaskListItem struct {
+	models.Task
+	Version string `json:"version"`
+}
+
 // TaskListResponse структура ответа со списком задач
 type TaskListResponse struct {
-	Tasks []models.Task `json:"tasks"`
+	Tasks []TaskListItem `json:"tasks"`
+	// Total - число всех подходящих под фильтр задач, а не только этой страницы.
+	// Передаётся только по запросу ?total=1, чтобы ответ без него оставался {"tasks": [...]}
+	Total *int `json:"total,omitempty"`
+	// NextCursor передаётся в ?cursor= для получения следующей страницы
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // HandleTaskList обрабатывает GET-запросы для получения списка задач
@@ -21,55 +44,168 @@ func (h *Handler) HandleTaskList(w http.ResponseWriter, r *http.Request) {
 	// Устанавливаем заголовок JSON
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	query := r.URL.Query()
+
 	// Лимит задач (по умолчанию 50)
-	limit := DefaultTaskLimit
-	queryLimit := r.URL.Query().Get("limit")
+	filter := db.TaskFilter{Limit: DefaultTaskLimit}
+	queryLimit := query.Get("limit")
 	if queryLimit != "" {
 		if parsedLimit, err := strconv.Atoi(queryLimit); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
+			filter.Limit = parsedLimit
 		}
 	}
 
-	// Выполняем запрос к базе данных
-	rows, err := h.DB.Query(
-		"SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date LIMIT ?",
-		limit,
-	)
-	if err != nil {
-		writeError(w, "Failed to retrieve tasks")
-		return
+	// Фильтр по тегам: ?tag=a&tag=b или ?tags=a,b
+	var tags []string
+	tags = append(tags, query["tag"]...)
+	if queryTags := query.Get("tags"); queryTags != "" {
+		tags = append(tags, strings.Split(queryTags, ",")...)
+	}
+	if len(tags) > 0 {
+		normalized, err := utils.NormalizeTags(tags)
+		if err != nil {
+			writeError(w, r, errTagFilterInvalid)
+			return
+		}
+		filter.Tags = normalized
 	}
-	defer rows.Close()
 
-	// Читаем данные из результата запроса
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		var id int64 // SQLite возвращает id в виде INTEGER
-		err := rows.Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+	// Фильтр по проекту: ?project_id=N или ?project_id=none для задач без проекта
+	filter.ProjectID = query.Get("project_id")
+	if filter.ProjectID != "" && filter.ProjectID != db.ProjectNone {
+		if _, err := strconv.Atoi(filter.ProjectID); err != nil {
+			writeError(w, r, errProjectFilterInvalid)
+			return
+		}
+	}
+	filter.IncludeArchived = query.Get("archived") == "1"
+
+	// Сортировка: ?sort=-priority,date (ключи date, priority, title, created).
+	// Без неё список упорядочен по дате, а результаты поиска - по релевантности.
+	if querySort := query.Get("sort"); querySort != "" {
+		sort, err := db.ParseTaskSort(querySort)
 		if err != nil {
-			writeError(w, "Failed to parse tasks")
+			writeError(w, r, errSortInvalid)
+			return
+		}
+		filter.Sort = sort
+	}
+
+	// Поиск: строка вида dd.mm.yyyy ищет задачи на эту дату, иначе - по заголовку и комментарию
+	if search := strings.TrimSpace(query.Get("search")); search != "" {
+		if date, err := time.Parse(searchDateFormat, search); err == nil {
+			filter.Date = date.Format(constants.DateFormat)
+		} else {
+			filter.Search = search
+		}
+	}
+
+	// Диапазон дат: ?from=YYYYMMDD&to=YYYYMMDD (границы включаются)
+	for _, bound := range []struct {
+		name string
+		dest *string
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := query.Get(bound.name)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(constants.DateFormat, value); err != nil {
+			writeError(w, r, errDateFilterInvalid.with(bound.name))
+			return
+		}
+		*bound.dest = value
+	}
+	if filter.From != "" && filter.To != "" && filter.From > filter.To {
+		writeError(w, r, errDateRangeInvalid)
+		return
+	}
+
+	// Просроченные задачи: дата раньше сегодняшней
+	switch query.Get("overdue") {
+	case "", "0":
+	case "1":
+		filter.Before = utils.NormalizeDate(time.Now()).Format(constants.DateFormat)
+	default:
+		writeError(w, r, errOverdueInvalid)
+		return
+	}
+
+	// Повторение: ?repeat=none|any|d|y
+	if repeat := query.Get("repeat"); repeat != "" {
+		if repeat != db.RepeatNone && repeat != db.RepeatAny && !slices.Contains(db.RepeatTypes, repeat) {
+			writeError(w, r, errRepeatFilterInvalid)
 			return
 		}
-		// Преобразуем id в строку
-		task.ID = strconv.FormatInt(id, 10)
-		tasks = append(tasks, task)
+		filter.Repeat = repeat
+	}
+
+	// Комментарий: ?has_comment=1 - только с комментарием, 0 - только без него
+	switch value := query.Get("has_comment"); value {
+	case "":
+	case "1", "0":
+		hasComment := value == "1"
+		filter.HasComment = &hasComment
+	default:
+		writeError(w, r, errHasCommentInvalid)
+		return
 	}
 
-	// Обрабатываем ошибку после завершения итерации
-	if err := rows.Err(); err != nil {
-		writeError(w, "Error iterating over rows")
+	switch mode := query.Get("tag_mode"); mode {
+	case "", db.TagModeAny:
+		filter.TagMode = db.TagModeAny
+	case db.TagModeAll:
+		filter.TagMode = db.TagModeAll
+	default:
+		writeError(w, r, errTagModeInvalid)
 		return
 	}
 
-	// Если задач нет, возвращаем пустой список
-	if tasks == nil {
-		tasks = []models.Task{}
+	// Курсор следующей страницы из next_cursor предыдущего ответа
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := db.ParseCursor(cursor)
+		if err != nil {
+			writeError(w, r, errCursorInvalid)
+			return
+		}
+		filter.After = &after
+	}
+
+	// Выполняем запрос к базе данных
+	tasks, next, err := db.ListTasksPage(r.Context(), h.DB, filter)
+	if errors.Is(err, db.ErrCursorSort) {
+		writeError(w, r, errCursorSort)
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, errTaskListFailed)
+		return
 	}
 
 	// Формируем и отправляем JSON-ответ
-	response := TaskListResponse{Tasks: tasks}
+	response := TaskListResponse{Tasks: make([]TaskListItem, len(tasks))}
+	for i, task := range tasks {
+		response.Tasks[i] = TaskListItem{Task: task, Version: strconv.Itoa(task.Version)}
+	}
+	total, err := db.CountTasks(r.Context(), h.DB, filter)
+	if err != nil {
+		writeDBError(w, r, err, errTaskListFailed)
+		return
+	}
+	if query.Get("total") == "1" {
+		response.Total = &total
+	}
+	// Заголовок X-Total-Count устарел: прежние клиенты читают его до следующего
+	// выпуска, новые запрашивают поле total
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if next != nil {
+		response.NextCursor = next.String()
+	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		writeError(w, "Failed to encode tasks")
+		writeError(w, r, errEncodeResponse)
 	}
 }
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// apiV2Tasks - адрес коллекции задач во второй версии API
+const apiV2Tasks = "/api/v2/tasks"
+
+// HandleTasksV2 обрабатывает коллекцию /api/v2/tasks:
+// GET возвращает список задач (параметры те же, что у /api/tasks), POST создаёт задачу
+func (h *Handler) HandleTasksV2(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.HandleTaskList(w, r)
+	case http.MethodPost:
+		h.createTaskV2(w, r)
+	default:
+		writeMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// HandleTaskV2 обрабатывает задачу /api/v2/tasks/{id}.
+// Запросы выполняются так же, как в первой версии, но идентификатор берётся из пути.
+func (h *Handler) HandleTaskV2(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getTask(w, r)
+	case http.MethodPut:
+		h.editTask(w, r)
+	case http.MethodPatch:
+		h.patchTask(w, r)
+	case http.MethodDelete:
+		h.deleteTask(w, r)
+	default:
+		writeMethodNotAllowed(w, r, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+// HandleTaskDoneV2 завершает задачу: POST /api/v2/tasks/{id}/done
+func (h *Handler) HandleTaskDoneV2(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	h.HandleTaskDone(w, r)
+}
+
+// createTaskV2 создаёт задачу и отвечает 201 Created: адрес задачи передаётся
+// в заголовке Location, версия - в ETag, в теле возвращается сохранённая задача
+func (h *Handler) createTaskV2(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	task, ok := h.createTask(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Location", apiV2Tasks+"/"+task.ID)
+	w.Header().Set("ETag", formatETag(task.Version))
+	w.WriteHeader(http.StatusCreated)
+	// Заголовки уже отправлены, поэтому ошибку можно только записать в лог
+	if err := json.NewEncoder(w).Encode(task); err != nil {
+		log.Printf("[ERROR] create task: %v", err)
+	}
+}
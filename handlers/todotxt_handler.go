@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go_final_project/db"
+	"go_final_project/models"
+	"go_final_project/todotxt"
+)
+
+// TodoTxtImportItem - результат импорта одной строки todo.txt (строки нумеруются с 1)
+type TodoTxtImportItem struct {
+	Line    int    `json:"line"`
+	Title   string `json:"title"`
+	Status  string `json:"status"`
+	ID      string `json:"id,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// TodoTxtImportReport - результат импорта файла todo.txt
+type TodoTxtImportReport struct {
+	DryRun   bool                `json:"dry_run"`
+	Total    int                 `json:"total"`
+	Imported int                 `json:"imported"`
+	Skipped  int                 `json:"skipped"`
+	Failed   int                 `json:"failed"`
+	Items    []TodoTxtImportItem `json:"items"`
+}
+
+// HandleTodoTxt выгружает задачи в формате todo.txt (GET)
+// или загружает их из него (POST, dry_run=1 - только проверка)
+func (h *Handler) HandleTodoTxt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="todo.txt"`)
+		// Заголовки уже отправлены, поэтому ошибку можно только записать в лог
+		if err := h.ExportTodoTxt(r.Context(), w); err != nil {
+			log.Printf("[ERROR] todo.txt export: %v", err)
+		}
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+		report, err := h.ImportTodoTxt(r.Context(), r.Body, actorFromRequest(r), requestLanguage(r), r.URL.Query().Get("dry_run") == "1")
+		if err != nil {
+			writeDBError(w, r, err, errImportFailed)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Printf("[ERROR] todo.txt import report: %v", err)
+		}
+	default:
+		writeMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// ExportTodoTxt пишет все задачи (включая задачи архивных проектов) в формате todo.txt
+func (h *Handler) ExportTodoTxt(ctx context.Context, w io.Writer) error {
+	projects, err := db.ListProjects(ctx, h.DB, true)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string, len(projects))
+	for _, project := range projects {
+		names[project.ID] = project.Name
+	}
+
+	bw := bufio.NewWriter(w)
+	filter := db.TaskFilter{IncludeArchived: true, Sort: db.DefaultSort, Limit: -1}
+	err = db.ForEachTask(ctx, h.DB, filter, func(task models.Task) error {
+		_, err := bw.WriteString(todotxt.Format(task, names[task.ProjectID]) + "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ImportTodoTxt загружает задачи из todo.txt от имени actor.
+// Строки проверяются по тем же правилам, что и при добавлении через /api/task;
+// корректные строки добавляются, выполненные (x) пропускаются.
+// Проекты ищутся по названию без учёта регистра, отсутствующие создаются
+// в транзакции импорта.
+// Сообщения об ошибках - на языке lang (пустая строка - язык по умолчанию).
+func (h *Handler) ImportTodoTxt(ctx context.Context, r io.Reader, actor, lang string, dryRun bool) (TodoTxtImportReport, error) {
+	report := TodoTxtImportReport{DryRun: dryRun, Items: []TodoTxtImportItem{}}
+
+	projects, err := db.ListProjects(ctx, h.DB, true)
+	if err != nil {
+		return report, err
+	}
+	// Существующие проекты нужны для проверки задач: в архивный проект задачу не добавить
+	projectIDs := make(map[string]string, len(projects))
+	for _, project := range projects {
+		projectIDs[todoTxtProjectKey(project.Name)] = project.ID
+	}
+
+	var tasks []models.Task
+	var projectNames []string // названия новых проектов по индексу в tasks
+	var imported []int        // индексы в report.Items для добавляемых задач
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		report.Total++
+		item := TodoTxtImportItem{Line: line}
+		parsed, err := todotxt.Parse(text)
+		item.Title = parsed.Task.Title
+		switch {
+		case err != nil:
+			item.Status = itemFailed
+			item.Code, item.Error = errEntryParse.Code, errEntryParse.message(lang)
+		case parsed.Done:
+			item.Status = itemSkipped
+		default:
+			task := parsed.Task
+			var warnings []string
+			if parsed.Unconverted != "" {
+				warnings = append(warnings, message(lang, repeatWarning, parsed.Unconverted))
+			}
+			if len(parsed.Projects) > 1 {
+				warnings = append(warnings, message(lang, projectWarning))
+			}
+			item.Warning = strings.Join(warnings, "; ")
+			var projectName string
+			if len(parsed.Projects) > 0 {
+				if id, ok := projectIDs[todoTxtProjectKey(parsed.Projects[0])]; ok {
+					task.ProjectID = id
+				} else {
+					projectName = parsed.Projects[0]
+				}
+			}
+
+			if e := h.prepareImportedTask(ctx, &task); e != nil {
+				item.Status = itemFailed
+				item.Code, item.Error = e.Code, e.message(lang)
+				break
+			}
+			item.Status = itemImported
+			tasks = append(tasks, task)
+			projectNames = append(projectNames, projectName)
+			imported = append(imported, len(report.Items))
+		}
+
+		switch item.Status {
+		case itemSkipped:
+			report.Skipped++
+		case itemFailed:
+			report.Failed++
+		}
+		report.Items = append(report.Items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return report, err
+	}
+
+	if !dryRun && len(tasks) > 0 {
+		audit, err := newAuditRecord(actor, "", "", nil, nil)
+		if err != nil {
+			return report, err
+		}
+		ids, _, err := db.ImportTasks(ctx, h.DB, tasks, projectNames, false, audit)
+		if err != nil {
+			return report, err
+		}
+		for i, id := range ids {
+			report.Items[imported[i]].ID = strconv.FormatInt(id, 10)
+		}
+	}
+	report.Imported = len(tasks)
+	return report, nil
+}
+
+// todoTxtProjectKey приводит название проекта к виду для сравнения:
+// без учёта регистра и считая "_" равным пробелу, как при импорте
+func todoTxtProjectKey(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", " ")
+}
@@ -0,0 +1,83 @@
+// Package ical формирует и разбирает календари в формате iCalendar (RFC 5545).
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineLength - максимальная длина строки в октетах без учёта CRLF
+const maxLineLength = 75
+
+// Writer пишет строки календаря с переносом длинных строк.
+// Первая ошибка записи запоминается и возвращается из Flush.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewWriter создаёт Writer поверх w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Property пишет свойство как есть; значение должно быть уже экранировано
+func (w *Writer) Property(name, value string) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.w.WriteString(fold(name + ":" + value))
+}
+
+// Text пишет текстовое свойство, экранируя спецсимволы
+func (w *Writer) Text(name, value string) {
+	w.Property(name, EscapeText(value))
+}
+
+// Begin открывает компонент (VCALENDAR, VEVENT, VTODO)
+func (w *Writer) Begin(component string) {
+	w.Property("BEGIN", component)
+}
+
+// End закрывает компонент
+func (w *Writer) End(component string) {
+	w.Property("END", component)
+}
+
+// Flush дописывает буфер и возвращает первую ошибку записи
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// textEscaper экранирует значения типа TEXT
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// EscapeText экранирует обратную косую черту, точку с запятой, запятую и переводы строк
+func EscapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// fold разбивает строку на части не длиннее 75 октетов, не разрывая символы UTF-8.
+// Каждая следующая часть начинается с пробела.
+func fold(line string) string {
+	var b strings.Builder
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineLength - 1 // пробел в начале строки тоже считается
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,200 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Property - свойство компонента с параметрами; значение хранится без разэкранирования
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Component - компонент календаря (VCALENDAR, VEVENT, VTODO и т. д.)
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []*Component
+}
+
+// Get возвращает первое свойство с указанным именем
+func (c *Component) Get(name string) (Property, bool) {
+	for _, prop := range c.Properties {
+		if prop.Name == name {
+			return prop, true
+		}
+	}
+	return Property{}, false
+}
+
+// Text возвращает разэкранированное значение текстового свойства или пустую строку
+func (c *Component) Text(name string) string {
+	prop, ok := c.Get(name)
+	if !ok {
+		return ""
+	}
+	return UnescapeText(prop.Value)
+}
+
+// Parse разбирает календарь и возвращает корневой компонент VCALENDAR.
+// Имена компонентов, свойств и параметров приводятся к верхнему регистру.
+func Parse(r io.Reader) (*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var root *Component
+	var stack []*Component
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			c := &Component{Name: strings.ToUpper(prop.Value)}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, fmt.Errorf("line %d: unexpected data after END:%s", i+1, root.Name)
+				}
+				root = c
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", i+1, prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property outside of component", i+1)
+			}
+			c := stack[len(stack)-1]
+			c.Properties = append(c.Properties, prop)
+		}
+	}
+
+	if root == nil || root.Name != "VCALENDAR" {
+		return nil, errors.New("missing VCALENDAR")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// unfold читает строки и склеивает перенесённые (начинающиеся с пробела или табуляции)
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && line != "" && (line[0] == ' ' || line[0] == '\t') {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine разбирает строку вида NAME;PARAM=VALUE;PARAM="VALUE":значение
+func parseLine(line string) (Property, error) {
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return Property{}, errors.New("invalid content line")
+	}
+	prop := Property{Name: strings.ToUpper(line[:end])}
+
+	rest := line[end:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return Property{}, errors.New("invalid parameter")
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quote := strings.IndexByte(rest[1:], '"')
+			if quote < 0 {
+				return Property{}, errors.New("unterminated quoted parameter")
+			}
+			value = rest[1 : quote+1]
+			rest = rest[quote+2:]
+		} else {
+			stop := strings.IndexAny(rest, ";:")
+			if stop < 0 {
+				return Property{}, errors.New("invalid content line")
+			}
+			value = rest[:stop]
+			rest = rest[stop:]
+		}
+		if prop.Params == nil {
+			prop.Params = make(map[string]string)
+		}
+		prop.Params[name] = value
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return Property{}, errors.New("invalid content line")
+	}
+	prop.Value = rest[1:]
+	return prop, nil
+}
+
+// UnescapeText восстанавливает текст, экранированный по правилам EscapeText
+func UnescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// SplitList делит значение-список (например, CATEGORIES) по неэкранированным запятым
+// и разэкранирует элементы
+func SplitList(s string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			items = append(items, UnescapeText(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, UnescapeText(s[start:]))
+}
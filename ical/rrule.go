@@ -0,0 +1,97 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RepeatToRRule переводит правило повторения задачи в RRULE.
+// Возвращает false, если у правила нет точного аналога в iCalendar.
+func RepeatToRRule(repeat string) (string, bool) {
+	parts := strings.Fields(repeat)
+	if len(parts) == 0 {
+		return "", false
+	}
+
+	switch parts[0] {
+	case "d":
+		if len(parts) != 2 {
+			return "", false
+		}
+		days, err := strconv.Atoi(parts[1])
+		if err != nil || days <= 0 || days > 400 {
+			return "", false
+		}
+		if days == 1 {
+			return "FREQ=DAILY", true
+		}
+		return "FREQ=DAILY;INTERVAL=" + strconv.Itoa(days), true
+	case "y":
+		if len(parts) != 1 {
+			return "", false
+		}
+		return "FREQ=YEARLY", true
+	}
+	return "", false
+}
+
+// RRuleToRepeat переводит RRULE в правило повторения задачи.
+// Поддерживаются ежедневные (с интервалом до 400 дней), еженедельные без BYDAY
+// (как повтор через 7·N дней) и ежегодные без уточнений правила.
+// Для остальных правил возвращается ошибка с причиной.
+func RRuleToRepeat(rule string) (string, error) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", fmt.Errorf("invalid RRULE part %q", part)
+		}
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	interval := 1
+	if value, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid INTERVAL %q", value)
+		}
+		interval = n
+	}
+
+	for key := range parts {
+		switch key {
+		case "FREQ", "INTERVAL", "WKST":
+		case "COUNT", "UNTIL":
+			return "", fmt.Errorf("%s is not supported: tasks repeat without end", key)
+		default:
+			return "", fmt.Errorf("%s is not supported", key)
+		}
+	}
+
+	var days int
+	switch freq := parts["FREQ"]; freq {
+	case "DAILY":
+		days = interval
+	case "WEEKLY":
+		days = 7 * interval
+	case "YEARLY":
+		if interval != 1 {
+			return "", errors.New("yearly rules with INTERVAL are not supported")
+		}
+		return "y", nil
+	case "":
+		return "", errors.New("missing FREQ")
+	default:
+		return "", fmt.Errorf("FREQ=%s is not supported", freq)
+	}
+
+	if days > 400 {
+		return "", fmt.Errorf("interval of %d days exceeds 400", days)
+	}
+	return "d " + strconv.Itoa(days), nil
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"database/sql"
 	"log"
 	"net/http"
 	"os"
@@ -26,13 +25,33 @@ func main() {
 		dbPath = filepath.Join(workingDir, "scheduler.db")
 	}
 
+	// Команды командной строки (backup, restore) выполняются вместо запуска сервера
+	if len(os.Args) > 1 {
+		if err := runCommand(dbPath, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Проверяем и создаём базу данных при необходимости
 	if err := db.SetupDatabase(dbPath); err != nil {
 		log.Fatalf("Error with database: %v", err)
 	}
 
+	// Шифрование полей задач (TODO_ENCRYPTION_KEY или TODO_ENCRYPTION_KEY_FILE)
+	if err := setupEncryption(); err != nil {
+		log.Fatalf("Invalid encryption settings: %v", err)
+	}
+
+	// Настройки подключения: режим журнала, синхронизация, ожидание блокировок,
+	// размер пула, повторы и ограничение времени запроса (TODO_DB_*)
+	dbConfig, err := db.LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid database settings: %v", err)
+	}
+
 	// Инициализация подключения к базе данных
-	dbConn, err := sql.Open("sqlite", dbPath)
+	dbConn, err := db.Open(dbPath, dbConfig)
 	if err != nil {
 		log.Fatalf("Не удалось подключиться к базе данных: %v", err)
 	}
@@ -41,11 +60,77 @@ func main() {
 	// Инициализируем обработчики с передачей подключения к базе данных
 	handler := handlers.NewHandler(dbConn)
 
+	// Настройки хранения вложений
+	handler.Attachments, err = handlers.LoadAttachmentConfig()
+	if err != nil {
+		log.Fatalf("Invalid attachment settings: %v", err)
+	}
+
+	// Обязательный If-Match для изменения задач; TODO_REQUIRE_IF_MATCH=0 отключает
+	// требование для старых клиентов (переданный заголовок проверяется всегда)
+	handler.RequireIfMatch = os.Getenv("TODO_REQUIRE_IF_MATCH") != "0"
+
+	// Токен администратора; без него административный API отключён
+	handler.AdminToken = os.Getenv("TODO_ADMIN_TOKEN")
+
+	// Секретный токен подписки на календарь; без него подписка отключена
+	handler.CalendarToken = os.Getenv("TODO_CALENDAR_TOKEN")
+
+	// Язык сообщений об ошибках по умолчанию (ru или en); клиент может выбрать свой
+	if err := handlers.SetDefaultLanguage(os.Getenv("TODO_LANG")); err != nil {
+		log.Fatalf("Invalid language settings: %v", err)
+	}
+
 	// Устанавливаем маршруты
-	http.HandleFunc("/api/task", handler.HandleTask)          // Для действий с задачами
-	http.HandleFunc("/api/nextdate", handlers.HandleDate)     // Для расчёта следующей даты
-	http.HandleFunc("/api/tasks", handler.HandleTaskList)     // Для списка задач
-	http.HandleFunc("/api/task/done", handler.HandleTaskDone) // Для завершения задачи
+	http.HandleFunc("/api/task", handler.HandleTask)               // Для действий с задачами
+	http.HandleFunc("GET /api/nextdate", handlers.HandleDate)      // Для расчёта следующей даты
+	http.HandleFunc("GET /api/tasks", handler.HandleTaskList)      // Для списка задач
+	http.HandleFunc("POST /api/task/done", handler.HandleTaskDone) // Для завершения задачи
+	http.HandleFunc("/api/tasks/batch", handler.HandleTaskBatch)   // Для нескольких операций в одной транзакции
+
+	// Остальные методы получают 405 с заголовком Allow в общем формате ошибок
+	http.HandleFunc("/api/nextdate", handlers.MethodNotAllowed(http.MethodGet))
+	http.HandleFunc("/api/tasks", handlers.MethodNotAllowed(http.MethodGet))
+	http.HandleFunc("/api/task/done", handlers.MethodNotAllowed(http.MethodPost))
+
+	// Вторая версия API: идентификатор задачи в пути, действие выбирается методом
+	http.HandleFunc("/api/v2/tasks", handler.HandleTasksV2)              // GET - список, POST - создание
+	http.HandleFunc("/api/v2/tasks/{id}", handler.HandleTaskV2)          // GET, PUT, PATCH, DELETE
+	http.HandleFunc("/api/v2/tasks/{id}/done", handler.HandleTaskDoneV2) // POST - завершение задачи
+
+	// Журнал изменений
+	http.HandleFunc("/api/task/history", handler.HandleTaskHistory) // История одной задачи
+	http.HandleFunc("/api/history", handler.HandleHistory)          // История по всем задачам
+	http.HandleFunc("/api/task/revert", handler.HandleTaskRevert)   // Откат задачи к записи журнала
+
+	// Проекты
+	http.HandleFunc("/api/project", handler.HandleProject)      // Для действий с проектами
+	http.HandleFunc("/api/projects", handler.HandleProjectList) // Для списка проектов
+	http.HandleFunc("/api/task/move", handler.HandleTaskMove)   // Для переноса задачи в другой проект
+
+	// Подзадачи (чек-лист)
+	http.HandleFunc("/api/subtask", handler.HandleSubtask)                 // Для добавления и удаления подзадач
+	http.HandleFunc("/api/subtasks", handler.HandleSubtaskList)            // Для списка подзадач задачи
+	http.HandleFunc("/api/subtask/toggle", handler.HandleSubtaskToggle)    // Для отметки о выполнении
+	http.HandleFunc("/api/subtasks/reorder", handler.HandleSubtaskReorder) // Для изменения порядка
+
+	// Вложения
+	http.HandleFunc("/api/attachment", handler.HandleAttachment)      // Для загрузки, скачивания и удаления файлов
+	http.HandleFunc("/api/attachments", handler.HandleAttachmentList) // Для списка вложений задачи
+
+	// Импорт и экспорт
+	http.HandleFunc("/api/export", handler.HandleExport)   // Для выгрузки всех задач в JSON или CSV
+	http.HandleFunc("/api/import", handler.HandleImport)   // Для загрузки задач из JSON или CSV
+	http.HandleFunc("/api/todotxt", handler.HandleTodoTxt) // Для выгрузки и загрузки задач в формате todo.txt
+
+	// Календарь
+	http.HandleFunc("/api/calendar", handler.HandleCalendar)              // Для выгрузки задач в .ics
+	http.HandleFunc("/api/calendar/feed/", handler.HandleCalendarFeed)    // Для подписки на календарь по токену
+	http.HandleFunc("/api/calendar/import", handler.HandleCalendarImport) // Для загрузки задач из .ics
+
+	// Администрирование
+	http.HandleFunc("/api/admin/backup", handler.HandleBackup)       // Для скачивания резервной копии базы
+	http.HandleFunc("/api/admin/reencrypt", handler.HandleReencrypt) // Для перешифрования полей после смены ключа
 
 	// Получаем порт из переменной окружения (Задача со звёздочкой)
 	port := os.Getenv("TODO_PORT")
@@ -0,0 +1,13 @@
+package models
+
+// Attachment описывает файл, прикреплённый к задаче.
+// Path заполняется только для файлов, хранящихся на диске.
+type Attachment struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	CreatedAt   string `json:"created_at"`
+	Path        string `json:"-"`
+}
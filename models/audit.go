@@ -0,0 +1,37 @@
+package models
+
+import "encoding/json"
+
+// Действия, которые записываются в журнал изменений
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDone   = "done"
+	AuditDelete = "delete"
+	AuditRevert = "revert"
+)
+
+// AuditRecord описывает запись журнала изменений задачи.
+// Before и After содержат состояние задачи до и после изменения (null, если задачи не было).
+type AuditRecord struct {
+	ID        string          `json:"id"`
+	TaskID    string          `json:"task_id"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// TaskSnapshot - состояние задачи в записи журнала. В отличие от ответов API
+// снимок содержит версию задачи: по ней восстановленная задача получает
+// версию новее всех, которые уже видели клиенты.
+type TaskSnapshot struct {
+	Task
+	Version int `json:"version"`
+}
+
+// MarshalTaskSnapshot кодирует состояние задачи для записи журнала
+func MarshalTaskSnapshot(task Task) ([]byte, error) {
+	return json.Marshal(TaskSnapshot{Task: task, Version: task.Version})
+}
@@ -0,0 +1,9 @@
+package models
+
+// Project описывает проект (список задач) из таблицы projects
+type Project struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Archived bool   `json:"archived"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// Subtask описывает пункт чек-листа задачи из таблицы subtasks
+type Subtask struct {
+	ID       string `json:"id"`
+	TaskID   string `json:"task_id"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"`
+}
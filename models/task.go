@@ -2,9 +2,23 @@ package models
 
 // Task описывает задачу из таблицы scheduler
 type Task struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Title   string `json:"title"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	ID        string   `json:"id"`
+	Date      string   `json:"date"`
+	Title     string   `json:"title"`
+	Comment   string   `json:"comment"`
+	Repeat    string   `json:"repeat"`
+	Tags      []string `json:"tags,omitempty"`
+	ProjectID string   `json:"project_id,omitempty"`
+	Priority  int      `json:"priority,omitempty"`
+	CreatedAt string   `json:"created_at,omitempty"`
+	// Version увеличивается при каждом изменении и передаётся в заголовке ETag
+	Version int `json:"-"`
+	// Checklist заполняется только в списке задач и только при наличии подзадач
+	Checklist *Checklist `json:"checklist,omitempty"`
+}
+
+// Checklist - счётчики выполнения подзадач
+type Checklist struct {
+	Total int `json:"total"`
+	Done  int `json:"done"`
 }
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestV2 отправляет запрос к API и возвращает ответ и его тело
+func requestV2(t *testing.T, method, path, body string) (*http.Response, map[string]any) {
+	req, err := http.NewRequest(method, getURL(strings.TrimPrefix(path, "/")), strings.NewReader(body))
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var ret map[string]any
+	if len(data) > 0 {
+		assert.NoError(t, json.Unmarshal(data, &ret), string(data))
+	}
+	return resp, ret
+}
+
+func TestAPIv2(t *testing.T) {
+	conn := openDB(t)
+	defer conn.Close()
+
+	date := time.Now().AddDate(0, 0, 2).Format(`20060102`)
+
+	// Создание: 201, адрес задачи и версия в заголовках, задача в теле
+	resp, task := requestV2(t, http.MethodPost, "api/v2/tasks",
+		`{"date": "`+date+`", "title": "Задача v2", "tags": ["версия"]}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode, task)
+	id, _ := task["id"].(string)
+	require.NotEmpty(t, id)
+	defer func() {
+		_, err := conn.Exec("DELETE FROM scheduler WHERE id = ?", id)
+		assert.NoError(t, err)
+	}()
+	location := resp.Header.Get("Location")
+	assert.Equal(t, "/api/v2/tasks/"+id, location)
+	assert.Equal(t, `"1"`, resp.Header.Get("ETag"))
+	assert.Equal(t, "Задача v2", task["title"])
+	assert.Equal(t, []any{"версия"}, task["tags"])
+
+	resp, task = requestV2(t, http.MethodGet, location, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Задача v2", task["title"])
+	assert.Equal(t, date, task["date"])
+
+	// Изменение: идентификатор берётся из пути
+	resp, _ = requestV2(t, http.MethodPut, location, `{"date": "`+date+`", "title": "Изменена"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+	resp, ret := requestV2(t, http.MethodPut, location, `{"id": "999999", "date": "`+date+`", "title": "Чужая"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, "task_id_immutable", ret["code"])
+
+	resp, task = requestV2(t, http.MethodPatch, location, `{"comment": "из v2"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, task)
+	assert.Equal(t, "Изменена", task["title"])
+	assert.Equal(t, "из v2", task["comment"])
+
+	// Первая версия API видит те же данные
+	resp, task = requestV2(t, http.MethodGet, "api/task?id="+id, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Изменена", task["title"])
+
+	resp, ret = requestV2(t, http.MethodGet, "api/v2/tasks?search=Изменена", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	if tasks, ok := ret["tasks"].([]any); assert.True(t, ok) && assert.NotEmpty(t, tasks) {
+		assert.Equal(t, id, tasks[0].(map[string]any)["id"])
+	}
+
+	resp, ret = requestV2(t, http.MethodGet, "api/v2/tasks/abc", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, "task_id_invalid", ret["code"])
+
+	// Неподдерживаемый метод: 405 со списком допустимых методов
+	for _, v := range []struct {
+		method, path, allow string
+	}{
+		{http.MethodDelete, "api/v2/tasks", "GET, POST"},
+		{http.MethodPost, location, "GET, PUT, PATCH, DELETE"},
+		{http.MethodGet, location + "/done", "POST"},
+		{http.MethodPut, "api/tasks/batch", "POST"},
+	} {
+		resp, ret = requestV2(t, v.method, v.path, "")
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode, v)
+		assert.Equal(t, v.allow, resp.Header.Get("Allow"), v)
+		assert.Equal(t, "method_not_allowed", ret["code"], v)
+	}
+
+	// Завершение одноразовой задачи удаляет её
+	resp, _ = requestV2(t, http.MethodPost, location+"/done", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, ret = requestV2(t, http.MethodGet, location, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "task_not_found", ret["code"])
+	resp, _ = requestV2(t, http.MethodDelete, location, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
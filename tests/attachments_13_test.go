@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func uploadAttachment(t *testing.T, taskID, filename string, content []byte) map[string]any {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	_, err = fw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	resp, err := http.Post(getURL("api/attachment?task_id="+taskID), mw.FormDataContentType(), &buf)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&m))
+	return m
+}
+
+func TestAttachments(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		date:  time.Now().AddDate(0, 0, 1).Format(`20060102`),
+		title: "Оплатить счёт",
+	})
+
+	content := []byte("%PDF-1.4 счёт на оплату")
+	ret := uploadAttachment(t, id, "invoice.pdf", content)
+	assert.NotNil(t, ret["id"])
+	attachID := fmt.Sprint(ret["id"])
+
+	resp, err := http.Get(getURL("api/attachment?id=" + attachID))
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, content, body)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+
+	ret = uploadAttachment(t, "0", "invoice.pdf", content)
+	assert.NotEmpty(t, ret["error"])
+
+	// Вложения удаляются вместе с задачей
+	m, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+
+	m, err = postJSON("api/attachment?id="+attachID, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m["error"])
+}
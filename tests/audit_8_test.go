@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getHistory(t *testing.T, id string) []map[string]any {
+	body, err := requestJSON("api/task/history?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["records"]
+}
+
+func TestAudit(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	id := addTask(t, task{
+		date:  date,
+		title: "Написать отчёт",
+	})
+
+	ret, err := postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  date,
+		"title": "Написать годовой отчёт",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	records := getHistory(t, id)
+	assert.Len(t, records, 3)
+	if len(records) != 3 {
+		return
+	}
+	// Записи возвращаются от новых к старым
+	assert.Equal(t, "delete", records[0]["action"])
+	assert.Equal(t, "update", records[1]["action"])
+	assert.Equal(t, "create", records[2]["action"])
+	assert.Nil(t, records[0]["after"])
+
+	// Восстанавливаем задачу в состояние после редактирования
+	updateID := fmt.Sprint(records[1]["id"])
+	ret, err = postJSON("api/task/revert?id="+id+"&audit_id="+updateID, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, "Написать годовой отчёт", ret["title"])
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Написать годовой отчёт", task.Title)
+	assert.Len(t, getHistory(t, id), 4)
+
+	// Удалённая задача восстанавливается с версией новее удалённой (2),
+	// чтобы сохранённые клиентами ETag не подошли к восстановленной задаче
+	var version int
+	assert.NoError(t, db.Get(&version, `SELECT version FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, 3, version)
+
+	// Перенос и выполнение задачи тоже записываются в журнал
+	ret, err = postJSON("api/task/move?id="+id+"&project_id=", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	records = getHistory(t, id)
+	if assert.Len(t, records, 6) {
+		assert.Equal(t, "done", records[0]["action"])
+		assert.Equal(t, "update", records[1]["action"])
+		assert.Equal(t, "revert", records[2]["action"])
+	}
+
+	_, err = db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	"go_final_project/db"
+)
+
+func TestBackup(t *testing.T) {
+	conn := openDB(t)
+	defer conn.Close()
+
+	id := addTask(t, task{title: "Сделать резервную копию"})
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	backup := filepath.Join(dir, "backup.db")
+	assert.NoError(t, db.Backup(ctx, &db.DB{DB: conn.DB}, backup))
+	// Существующий файл не перезаписывается
+	assert.Error(t, db.Backup(ctx, &db.DB{DB: conn.DB}, backup))
+
+	version, err := db.ValidateBackup(backup)
+	assert.NoError(t, err)
+	assert.Equal(t, db.SchemaVersion(), version)
+	// Проверка открывает копию только на чтение и не создаёт рядом файлов журнала
+	files, err := filepath.Glob(backup + "*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{backup}, files)
+
+	garbage := filepath.Join(dir, "garbage.db")
+	assert.NoError(t, os.WriteFile(garbage, []byte("not a database"), 0o644))
+	_, err = db.ValidateBackup(garbage)
+	assert.Error(t, err)
+
+	// Восстановление в отдельный файл, а не в базу работающего сервера
+	target := filepath.Join(dir, "restored.db")
+	assert.NoError(t, db.RestoreDatabase(target, backup))
+
+	restored, err := sqlx.Connect("sqlite", target)
+	assert.NoError(t, err)
+	defer restored.Close()
+	var task Task
+	err = restored.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Сделать резервную копию", task.Title)
+
+	// Изменения текущей базы, оставшиеся только в -wal, сохраняются вместе с ней
+	live := filepath.Join(dir, "live.db")
+	wal, err := sqlx.Connect("sqlite", filepath.Join(dir, "wal.db"))
+	assert.NoError(t, err)
+	_, err = wal.Exec(`PRAGMA journal_mode = WAL; PRAGMA wal_autocheckpoint = 0;
+		CREATE TABLE notes (text TEXT); INSERT INTO notes VALUES ('только в журнале')`)
+	assert.NoError(t, err)
+	for _, suffix := range []string{"", "-wal"} {
+		data, err := os.ReadFile(filepath.Join(dir, "wal.db") + suffix)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(live+suffix, data, 0o644))
+	}
+	assert.NoError(t, wal.Close())
+
+	assert.NoError(t, db.RestoreDatabase(live, backup))
+	saved, err := filepath.Glob(live + ".before-restore-*")
+	assert.NoError(t, err)
+	if assert.Len(t, saved, 2) { // база и её -wal
+		before, err := sqlx.Connect("sqlite", saved[0])
+		assert.NoError(t, err)
+		defer before.Close()
+		var note string
+		assert.NoError(t, before.Get(&note, `SELECT text FROM notes`))
+		assert.Equal(t, "только в журнале", note)
+	}
+
+	_, err = conn.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type batchResponse struct {
+	Code      string `json:"code"`
+	Error     string `json:"error"`
+	Mode      string `json:"mode"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	Results   []struct {
+		Index  int    `json:"index"`
+		Op     string `json:"op"`
+		Status string `json:"status"`
+		ID     string `json:"id"`
+		Code   string `json:"code"`
+		Error  string `json:"error"`
+	} `json:"results"`
+}
+
+// postBatch отправляет операции в /api/tasks/batch и возвращает код ответа и результат
+func postBatch(t *testing.T, mode string, ops ...map[string]any) (int, batchResponse) {
+	data, err := json.Marshal(map[string]any{"mode": mode, "operations": ops})
+	require.NoError(t, err)
+	resp, err := http.Post(getURL("api/tasks/batch"), "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var ret batchResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	return resp.StatusCode, ret
+}
+
+func batchStatuses(ret batchResponse) []string {
+	statuses := []string{}
+	for _, result := range ret.Results {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
+
+func TestTaskBatch(t *testing.T) {
+	conn := openDB(t)
+	defer conn.Close()
+
+	_, err := conn.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	taskCount := func() int {
+		var n int
+		assert.NoError(t, conn.Get(&n, "SELECT COUNT(*) FROM scheduler"))
+		return n
+	}
+
+	// Создание нескольких задач одним запросом
+	status, ret := postBatch(t, "",
+		map[string]any{"op": "create", "task": map[string]any{"date": tomorrow, "title": "Первая"}},
+		map[string]any{"op": "create", "task": map[string]any{"date": tomorrow, "title": "Вторая", "tags": []string{"пакет"}}},
+		map[string]any{"op": "create", "task": map[string]any{"date": tomorrow, "title": "Повтор", "repeat": "d 2"}},
+	)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "atomic", ret.Mode)
+	assert.Equal(t, []string{"ok", "ok", "ok"}, batchStatuses(ret))
+	assert.Equal(t, 3, ret.Succeeded)
+	assert.Equal(t, 3, taskCount())
+	first, second, repeating := ret.Results[0].ID, ret.Results[1].ID, ret.Results[2].ID
+	assert.Equal(t, []string{"пакет"}, getTaskTags(t, second))
+
+	// В режиме "всё или ничего" ошибка проверки отменяет весь запрос
+	status, ret = postBatch(t, "atomic",
+		map[string]any{"op": "delete", "id": first},
+		map[string]any{"op": "create", "task": map[string]any{"title": ""}},
+	)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.NotEmpty(t, ret.Error)
+	assert.Equal(t, []string{"skipped", "error"}, batchStatuses(ret))
+	assert.Equal(t, 3, taskCount())
+
+	// Ошибка при выполнении откатывает уже выполненные операции
+	status, ret = postBatch(t, "atomic",
+		map[string]any{"op": "delete", "id": first},
+		map[string]any{"op": "delete", "id": first},
+		map[string]any{"op": "delete", "id": second},
+	)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, "batch_aborted", ret.Code)
+	assert.Equal(t, []string{"rolled_back", "error", "skipped"}, batchStatuses(ret))
+	assert.Equal(t, 3, taskCount())
+
+	// В режиме best_effort выполняются все корректные операции
+	status, ret = postBatch(t, "best_effort",
+		map[string]any{"op": "update", "id": first, "task": map[string]any{"date": tomorrow, "title": "Первая изменена"}},
+		map[string]any{"op": "delete", "id": "999999"},
+		map[string]any{"op": "done", "id": repeating},
+		map[string]any{"op": "update", "id": second, "version": 100, "task": map[string]any{"date": tomorrow, "title": "Вторая"}},
+		map[string]any{"op": "delete", "id": second},
+		map[string]any{"op": "archive", "id": second},
+	)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"ok", "error", "ok", "error", "ok", "error"}, batchStatuses(ret))
+	assert.Equal(t, 3, ret.Succeeded)
+	assert.Equal(t, 3, ret.Failed)
+	assert.Equal(t, 2, taskCount())
+
+	var title string
+	assert.NoError(t, conn.Get(&title, "SELECT title FROM scheduler WHERE id = ?", first))
+	assert.Equal(t, "Первая изменена", title)
+
+	var date string
+	assert.NoError(t, conn.Get(&date, "SELECT date FROM scheduler WHERE id = ?", repeating))
+	assert.NotEqual(t, tomorrow, date)
+
+	// Каждая выполненная операция попадает в журнал
+	history := getHistory(t, first)
+	assert.Equal(t, "update", history[0]["action"])
+
+	// Операции над одной задачей видят результат предыдущих: update после done
+	// сверяется с версией и датой, которые задача получила при завершении
+	status, ret = postBatch(t, "atomic",
+		map[string]any{"op": "done", "id": repeating, "version": 2},
+		map[string]any{"op": "update", "id": repeating, "version": 3, "task": map[string]any{"date": tomorrow, "title": "Повтор изменён", "repeat": "d 2"}},
+	)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"ok", "ok"}, batchStatuses(ret))
+	history = getHistory(t, repeating)
+	if assert.GreaterOrEqual(t, len(history), 2) {
+		assert.Equal(t, "update", history[0]["action"])
+		assert.Equal(t, "done", history[1]["action"])
+		// Снимок до изменения записан по состоянию после done, а не до начала пакета
+		before := history[0]["before"].(map[string]any)
+		assert.Equal(t, history[1]["after"].(map[string]any)["date"], before["date"])
+	}
+
+	// Устаревшая версия отклоняется при выполнении операции
+	_, ret = postBatch(t, "best_effort", map[string]any{"op": "done", "id": repeating, "version": 3})
+	if assert.Len(t, ret.Results, 1) {
+		assert.Equal(t, "precondition_failed", ret.Results[0].Code)
+	}
+
+	status, _ = postBatch(t, "sometimes", map[string]any{"op": "delete", "id": first})
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+
+	_, err = conn.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
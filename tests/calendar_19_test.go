@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_final_project/ical"
+)
+
+// getCalendar возвращает код ответа и календарь, развернув перенесённые строки
+func getCalendar(t *testing.T, path string) (int, string) {
+	resp, err := http.Get(getURL(path))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return resp.StatusCode, strings.ReplaceAll(string(body), "\r\n ", "")
+}
+
+func TestRepeatToRRule(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		rule   string
+		ok     bool
+	}{
+		{"d 1", "FREQ=DAILY", true},
+		{"d 7", "FREQ=DAILY;INTERVAL=7", true},
+		{"y", "FREQ=YEARLY", true},
+		{"d 401", "", false},
+		{"w 1,3", "", false},
+		{"", "", false},
+	}
+	for _, v := range tbl {
+		rule, ok := ical.RepeatToRRule(v.repeat)
+		assert.Equal(t, v.ok, ok, v.repeat)
+		assert.Equal(t, v.rule, rule, v.repeat)
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	title := "Оплатить счета; аренда, свет и воду — не забыть до конца месяца, иначе будут пени"
+	id := addTask(t, task{title: title, comment: "Счёт\nпо почте", repeat: "d 7"})
+	defer func() {
+		_, err := db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+		assert.NoError(t, err)
+	}()
+
+	code, body := getCalendar(t, "api/calendar")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(body, "END:VCALENDAR\r\n"))
+	assert.Contains(t, body, "BEGIN:VEVENT\r\nUID:task-"+id+"@go_final_project\r\n")
+	assert.Contains(t, body, `SUMMARY:Оплатить счета\; аренда\, свет`)
+	assert.Contains(t, body, `DESCRIPTION:Счёт\nпо почте`)
+	assert.Contains(t, body, "RRULE:FREQ=DAILY;INTERVAL=7\r\n")
+	assert.Contains(t, body, "X-SCHEDULER-REPEAT:d 7\r\n")
+
+	code, body = getCalendar(t, "api/calendar?component=vtodo")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "BEGIN:VTODO\r\n")
+	assert.NotContains(t, body, "BEGIN:VEVENT")
+
+	code, _ = getCalendar(t, "api/calendar?component=journal")
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+	// Подписка доступна только по секретному токену
+	code, _ = getCalendar(t, "api/calendar/feed/wrong-token.ics")
+	if CalendarToken == "" {
+		assert.Equal(t, http.StatusForbidden, code)
+		return
+	}
+	assert.Equal(t, http.StatusNotFound, code)
+	code, body = getCalendar(t, "api/calendar/feed/"+CalendarToken+".ics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "UID:task-"+id+"@go_final_project")
+}
@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_final_project/ical"
+)
+
+type calendarImportReport struct {
+	DryRun   bool `json:"dry_run"`
+	Total    int  `json:"total"`
+	Imported int  `json:"imported"`
+	Skipped  int  `json:"skipped"`
+	Failed   int  `json:"failed"`
+	Items    []struct {
+		Item    int    `json:"item"`
+		Title   string `json:"title"`
+		Status  string `json:"status"`
+		ID      string `json:"id"`
+		Repeat  string `json:"repeat"`
+		Error   string `json:"error"`
+		Warning string `json:"warning"`
+	} `json:"items"`
+}
+
+func importCalendar(t *testing.T, query, data string) (int, calendarImportReport) {
+	resp, err := http.Post(getURL("api/calendar/import?"+query), "text/calendar", strings.NewReader(data))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var report calendarImportReport
+	if resp.StatusCode == http.StatusOK {
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	}
+	return resp.StatusCode, report
+}
+
+func TestRRuleToRepeat(t *testing.T) {
+	tbl := []struct {
+		rule   string
+		repeat string
+	}{
+		{"FREQ=DAILY", "d 1"},
+		{"FREQ=DAILY;INTERVAL=3", "d 3"},
+		{"freq=weekly;interval=2;wkst=MO", "d 14"},
+		{"FREQ=YEARLY", "y"},
+		{"FREQ=WEEKLY;BYDAY=MO,WE", ""},
+		{"FREQ=MONTHLY", ""},
+		{"FREQ=DAILY;COUNT=5", ""},
+		{"FREQ=DAILY;INTERVAL=500", ""},
+		{"FREQ=YEARLY;INTERVAL=2", ""},
+		{"INTERVAL=2", ""},
+	}
+	for _, v := range tbl {
+		repeat, err := ical.RRuleToRepeat(v.rule)
+		assert.Equal(t, v.repeat, repeat, v.rule)
+		assert.Equal(t, v.repeat == "", err != nil, v.rule)
+	}
+}
+
+func TestCalendarImport(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	date := time.Now().AddDate(0, 0, 2).Format(`20060102`)
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Test//EN",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Moscow",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:1@test",
+		"DTSTART;VALUE=DATE:" + date,
+		`SUMMARY:Импорт: встреча\, обсуждение`,
+		`DESCRIPTION:Первая строка\nвторая строка с очень длинным текстом, который переносится по`,
+		"  правилам iCalendar",
+		"CATEGORIES:Работа,Встречи",
+		"PRIORITY:1",
+		"RRULE:FREQ=WEEKLY;INTERVAL=1",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:2@test",
+		"DTSTART;TZID=Europe/Moscow:" + date + "T100000",
+		"SUMMARY:Импорт: ежемесячный отчёт",
+		"RRULE:FREQ=MONTHLY;BYMONTHDAY=1",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:3@test",
+		"SUMMARY:Импорт: уже сделано",
+		"STATUS:COMPLETED",
+		"END:VTODO",
+		"BEGIN:VEVENT",
+		"UID:4@test",
+		"DTSTART:2026-01-01",
+		"SUMMARY:Импорт: неверная дата",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:5@test",
+		"DTSTART;VALUE=DATE:" + date,
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	// Проверка без записи
+	code, report := importCalendar(t, "dry_run=1", data)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 5, report.Total)
+	total, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before, total)
+
+	code, report = importCalendar(t, "", data)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 2, report.Failed)
+	if assert.Len(t, report.Items, 5) {
+		assert.Equal(t, "imported", report.Items[0].Status)
+		assert.Equal(t, "d 7", report.Items[0].Repeat)
+		assert.Empty(t, report.Items[0].Warning)
+
+		// Правило без аналога: задача добавляется без повторения, причина сообщается
+		assert.Equal(t, "imported", report.Items[1].Status)
+		assert.Empty(t, report.Items[1].Repeat)
+		assert.Contains(t, report.Items[1].Warning, "FREQ=MONTHLY")
+		assert.Contains(t, report.Items[1].Warning, "Правило повторения")
+
+		assert.Equal(t, "skipped", report.Items[2].Status)
+		assert.Equal(t, "Запись выполнена или отменена", report.Items[2].Warning)
+		assert.Equal(t, "error", report.Items[3].Status)
+		assert.Equal(t, "error", report.Items[4].Status)
+		assert.NotEmpty(t, report.Items[4].Error)
+	}
+
+	var task Task
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, report.Items[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Импорт: встреча, обсуждение", task.Title)
+	assert.Equal(t, "Первая строка\nвторая строка с очень длинным текстом, который переносится по правилам iCalendar", task.Comment)
+	assert.Equal(t, date, task.Date)
+	assert.Equal(t, 3, task.Priority)
+	assert.Equal(t, []string{"встречи", "работа"}, getTaskTags(t, report.Items[0].ID))
+
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, report.Items[1].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, date, task.Date)
+
+	_, err = db.Exec(`DELETE FROM scheduler WHERE title LIKE 'Импорт:%'`)
+	assert.NoError(t, err)
+
+	// Некорректное X-SCHEDULER-REPEAT отбрасывается, а повторение берётся из RRULE
+	code, report = importCalendar(t, "", strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:6@test",
+		"DTSTART;VALUE=DATE:" + date,
+		"SUMMARY:Импорт: изменённое правило",
+		"RRULE:FREQ=DAILY;INTERVAL=2",
+		"X-SCHEDULER-REPEAT:w 8",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n"))
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, report.Items, 1) {
+		assert.Equal(t, "imported", report.Items[0].Status)
+		assert.Equal(t, "d 2", report.Items[0].Repeat)
+		assert.Contains(t, report.Items[0].Warning, "w 8")
+	}
+	_, err = db.Exec(`DELETE FROM scheduler WHERE title LIKE 'Импорт:%'`)
+	assert.NoError(t, err)
+
+	// Повреждённый файл
+	code, _ = importCalendar(t, "", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
+
+// getTaskTags возвращает теги задачи из /api/task
+func getTaskTags(t *testing.T, id string) []string {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var task struct {
+		Tags []string `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &task))
+	return task.Tags
+}
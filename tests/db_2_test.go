@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"database/sql"
 	"os"
 	"testing"
 	"time"
@@ -11,11 +12,15 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID        int64         `db:"id"`
+	Date      string        `db:"date"`
+	Title     string        `db:"title"`
+	Comment   string        `db:"comment"`
+	Repeat    string        `db:"repeat"`
+	ProjectID sql.NullInt64 `db:"project_id"`
+	Priority  int           `db:"priority"`
+	CreatedAt string        `db:"created_at"`
+	Version   int           `db:"version"`
 }
 
 func count(db *sqlx.DB) (int, error) {
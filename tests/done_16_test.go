@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// doneParallel отправляет параллельные запросы /api/task/done и возвращает коды ответов
+func doneParallel(t *testing.T, id, etag string, workers int) []int {
+	var wg sync.WaitGroup
+	codes := make(chan int, workers)
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			req, err := http.NewRequest(http.MethodPost, getURL("api/task/done?id="+id), nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+			if etag != "" {
+				req.Header.Set("If-Match", etag)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp.Body.Close()
+			codes <- resp.StatusCode
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(codes)
+
+	var result []int
+	for code := range codes {
+		result = append(result, code)
+	}
+	return result
+}
+
+func TestDoneConcurrent(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		title:  "Полить цветы",
+		repeat: "d 3",
+	})
+
+	// Клиенты, видевшие одну и ту же версию: задачу продвигает ровно один
+	var ok int
+	for _, code := range doneParallel(t, id, `"1"`, 10) {
+		if code == http.StatusOK {
+			ok++
+		} else {
+			assert.Equal(t, http.StatusPreconditionFailed, code)
+		}
+	}
+	assert.Equal(t, 1, ok, "Задачу должен продвинуть ровно один запрос")
+
+	var task Task
+	err := db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), task.Date)
+
+	// Без If-Match каждый успешный запрос продвигает задачу ровно один раз,
+	// а проигравшие гонку получают 409, а не ошибку
+	ok = 0
+	for _, code := range doneParallel(t, id, "", 10) {
+		if code == http.StatusOK {
+			ok++
+		} else {
+			assert.Equal(t, http.StatusConflict, code)
+		}
+	}
+	assert.GreaterOrEqual(t, ok, 1)
+
+	err = db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, 3*(ok+1)).Format(`20060102`), task.Date)
+
+	_, err = db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_final_project/db"
+	"go_final_project/models"
+)
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "encrypted.db")
+	assert.NoError(t, db.SetupDatabase(path))
+	conn, err := db.Open(path, db.DefaultConfig())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	defer db.SetEncryption(nil)
+
+	// Ключ неверной длины отклоняется
+	assert.Error(t, db.SetEncryption(&db.EncryptionConfig{Key: []byte("short")}))
+	assert.NoError(t, db.SetEncryption(&db.EncryptionConfig{Key: oldKey, Fields: []string{db.FieldComment}}))
+
+	id, err := db.AddTask(ctx, conn, models.Task{Date: "20300101", Title: "Договор", Comment: "Паспорт клиента 1234"})
+	assert.NoError(t, err)
+	_, err = db.AddAuditRecord(ctx, conn, models.AuditRecord{
+		TaskID: strconv.FormatInt(id, 10), Action: models.AuditCreate, Actor: "test",
+		After: json.RawMessage(`{"comment":"Паспорт клиента 1234"}`), CreatedAt: "2030-01-01T00:00:00Z",
+	})
+	assert.NoError(t, err)
+
+	// В базе хранится только шифротекст
+	var title, comment, after string
+	assert.NoError(t, conn.QueryRow(`SELECT title, comment FROM scheduler WHERE id = ?`, id).Scan(&title, &comment))
+	assert.Equal(t, "Договор", title)
+	assert.True(t, strings.HasPrefix(comment, "enc:v1:"))
+	assert.NoError(t, conn.QueryRow(`SELECT after FROM audit_log`).Scan(&after))
+	assert.NotContains(t, after, "Паспорт")
+
+	task, err := db.GetTaskByID(ctx, conn, int(id))
+	assert.NoError(t, err)
+	assert.Equal(t, "Паспорт клиента 1234", task.Comment)
+
+	records, err := db.GetAuditRecords(ctx, conn, 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.JSONEq(t, `{"comment":"Паспорт клиента 1234"}`, string(records[0].After))
+	}
+
+	// Зашифрованные поля не участвуют в поиске
+	tasks, err := db.ListTasks(ctx, conn, db.TaskFilter{Search: "паспорт", Limit: -1})
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+	tasks, err = db.ListTasks(ctx, conn, db.TaskFilter{Search: "договор", Limit: -1})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	// Смена ключа: без прежнего ключа значение не расшифровывается, но задача и список
+	// читаются; после Reencrypt прежний ключ не нужен
+	assert.NoError(t, db.SetEncryption(&db.EncryptionConfig{Key: newKey, Fields: []string{db.FieldComment, db.FieldTitle}}))
+	task, err = db.GetTaskByID(ctx, conn, int(id))
+	assert.NoError(t, err)
+	assert.Equal(t, "Договор", task.Title)
+	assert.True(t, strings.HasPrefix(task.Comment, "enc:v1:"))
+	tasks, err = db.ListTasks(ctx, conn, db.TaskFilter{Limit: -1})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	assert.NoError(t, db.SetEncryption(&db.EncryptionConfig{
+		Key: newKey, OldKeys: [][]byte{oldKey}, Fields: []string{db.FieldComment, db.FieldTitle},
+	}))
+	n, err := db.Reencrypt(ctx, conn)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	// Открытый заголовок не остаётся ни в освободившихся страницах, ни в индексе поиска
+	for _, suffix := range []string{"", "-wal"} {
+		if data, err := os.ReadFile(path + suffix); err == nil {
+			assert.False(t, bytes.Contains(data, []byte("оговор")), "plaintext in %s", path+suffix)
+		}
+	}
+	// Повторный запуск ничего не меняет
+	n, err = db.Reencrypt(ctx, conn)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	assert.NoError(t, db.SetEncryption(&db.EncryptionConfig{Key: newKey, Fields: []string{db.FieldComment, db.FieldTitle}}))
+	task, err = db.GetTaskByID(ctx, conn, int(id))
+	assert.NoError(t, err)
+	assert.Equal(t, "Договор", task.Title)
+	assert.Equal(t, "Паспорт клиента 1234", task.Comment)
+	assert.NoError(t, conn.QueryRow(`SELECT title FROM scheduler WHERE id = ?`, id).Scan(&title))
+	assert.True(t, strings.HasPrefix(title, "enc:v1:"))
+
+	// Отказ от шифрования: поля, исключённые из настроек, расшифровываются
+	assert.NoError(t, db.SetEncryption(&db.EncryptionConfig{
+		Key: newKey, Fields: []string{db.FieldComment},
+	}))
+	_, err = db.Reencrypt(ctx, conn)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.QueryRow(`SELECT title FROM scheduler WHERE id = ?`, id).Scan(&title))
+	assert.Equal(t, "Договор", title)
+
+	// Открытое значение, похожее на шифротекст, хранится с отметкой и читается как было
+	id, err = db.AddTask(ctx, conn, models.Task{Date: "20300101", Title: "enc:v1:abc:zzz", Comment: "enc:plain:x"})
+	assert.NoError(t, err)
+	assert.NoError(t, conn.QueryRow(`SELECT title FROM scheduler WHERE id = ?`, id).Scan(&title))
+	assert.Equal(t, "enc:plain:enc:v1:abc:zzz", title)
+	task, err = db.GetTaskByID(ctx, conn, int(id))
+	assert.NoError(t, err)
+	assert.Equal(t, "enc:v1:abc:zzz", task.Title)
+	assert.Equal(t, "enc:plain:x", task.Comment)
+}
+
+func TestEncryptedPrefixTitle(t *testing.T) {
+	title := "enc:v1:abc:zzz"
+	ret, err := postJSON("api/task", map[string]any{"date": "20300101", "title": title}, http.MethodPost)
+	assert.NoError(t, err)
+	id, _ := ret["id"].(string)
+	if !assert.NotEmpty(t, id) {
+		return
+	}
+	defer func() {
+		_, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+	}()
+
+	// Заголовок не принимается за шифротекст ни в списке, ни при чтении задачи
+	assert.NotEmpty(t, getTaskTitles(t, ""))
+	assert.Contains(t, getTaskTitles(t, "search=zzz"), title)
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, title, task["title"])
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// problemRequest отправляет запрос и возвращает код ответа и тело ошибки в формате problem+json
+func problemRequest(t *testing.T, method, path, body string) (int, map[string]string) {
+	req, err := http.NewRequest(method, getURL(path), strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "application/problem+json", mediaType, path)
+
+	// Все поля строковые, как и в прежнем формате {"error": "..."}
+	var problem map[string]string
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem), path)
+	return resp.StatusCode, problem
+}
+
+func TestErrorResponses(t *testing.T) {
+	for _, v := range []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{http.MethodGet, "api/task?id=999999", "", http.StatusNotFound, "task_not_found"},
+		{http.MethodGet, "api/task?id=abc", "", http.StatusUnprocessableEntity, "task_id_invalid"},
+		{http.MethodGet, "api/task", "", http.StatusUnprocessableEntity, "task_id_required"},
+		{http.MethodPost, "api/task", "{", http.StatusBadRequest, "invalid_json"},
+		{http.MethodPost, "api/task", `{"title": ""}`, http.StatusUnprocessableEntity, "title_required"},
+		{http.MethodPost, "api/task", `{"title": "x", "date": "2024-01-01"}`, http.StatusUnprocessableEntity, "invalid_date"},
+		{http.MethodPost, "api/task", `{"title": "x", "project_id": "999999"}`, http.StatusUnprocessableEntity, "unknown_project"},
+		// Правило повторения проверяется и для будущей даты
+		{http.MethodPost, "api/task", `{"title": "x", "date": "20991231", "repeat": "w 8"}`, http.StatusUnprocessableEntity, "invalid_repeat"},
+		{http.MethodDelete, "api/task?id=999999", "", http.StatusNotFound, "task_not_found"},
+		{http.MethodPost, "api/task/done?id=999999", "", http.StatusNotFound, "task_not_found"},
+		{http.MethodGet, "api/project?id=999999", "", http.StatusNotFound, "project_not_found"},
+		{http.MethodGet, "api/tasks?sort=sideways", "", http.StatusUnprocessableEntity, "invalid_sort"},
+		{http.MethodGet, "api/nextdate?now=today&date=20240101&repeat=d+1", "", http.StatusUnprocessableEntity, "invalid_now"},
+		{http.MethodGet, "api/nextdate?now=20240101&date=2024-01-01&repeat=d+1", "", http.StatusUnprocessableEntity, "invalid_date"},
+		{http.MethodGet, "api/nextdate?now=20240101&date=20240101&repeat=w+8", "", http.StatusUnprocessableEntity, "invalid_repeat"},
+		{http.MethodPut, "api/tasks/batch", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodPut, "api/tasks", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodPut, "api/nextdate?now=20240101&date=20240101&repeat=d+1", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodDelete, "api/task/done?id=999999", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+	} {
+		status, problem := problemRequest(t, v.method, v.path, v.body)
+		assert.Equal(t, v.status, status, v.path)
+		assert.Equal(t, v.code, problem["code"], v.path)
+		assert.Equal(t, http.StatusText(v.status), problem["title"], v.path)
+		assert.NotEmpty(t, problem["error"], v.path)
+		assert.Equal(t, problem["error"], problem["detail"], v.path)
+	}
+
+	// Ошибки отдельных операций пакетного запроса содержат те же коды
+	_, ret := postBatch(t, "best_effort",
+		map[string]any{"op": "delete", "id": "999999"},
+		map[string]any{"op": "create", "task": map[string]any{"title": ""}},
+	)
+	if assert.Len(t, ret.Results, 2) {
+		assert.Equal(t, "task_not_found", ret.Results[0].Code)
+		assert.Equal(t, "title_required", ret.Results[1].Code)
+	}
+}
+
+func TestDoneStoredInvalidRepeat(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	// Правило, сохранённое до появления проверки, нельзя исправить при завершении
+	id := addTask(t, task{date: time.Now().Format(`20060102`), title: "Старое правило", repeat: "d 1"})
+	_, err := db.Exec(`UPDATE scheduler SET repeat = 'w 8' WHERE id = ?`, id)
+	require.NoError(t, err)
+
+	status, problem := problemRequest(t, http.MethodPost, "api/task/done?id="+id, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, "stored_repeat_invalid", problem["code"])
+
+	_, ret := postBatch(t, "best_effort", map[string]any{"op": "done", "id": id})
+	if assert.Len(t, ret.Results, 1) {
+		assert.Equal(t, "stored_repeat_invalid", ret.Results[0].Code)
+	}
+
+	_, err = db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+	assert.NoError(t, err)
+}
+
+func TestLegacyRouteMethods(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	id := addTask(t, task{date: date, title: "Проверка метода"})
+	defer func() {
+		_, err := db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+		assert.NoError(t, err)
+	}()
+
+	for _, v := range []struct {
+		method, path, allow string
+	}{
+		{http.MethodPut, "api/tasks", http.MethodGet},
+		{http.MethodPost, "api/nextdate?now=20240101&date=20240101&repeat=d+1", http.MethodGet},
+		{http.MethodDelete, "api/task/done?id=" + id, http.MethodPost},
+		{http.MethodGet, "api/task/done?id=" + id, http.MethodPost},
+	} {
+		req, err := http.NewRequest(v.method, getURL(v.path), nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode, v.method+" "+v.path)
+		assert.Equal(t, v.allow, resp.Header.Get("Allow"), v.method+" "+v.path)
+	}
+
+	// Запрос с неверным методом не завершил задачу
+	var count int
+	assert.NoError(t, db.Get(&count, `SELECT COUNT(*) FROM scheduler WHERE id = ?`, id))
+	assert.Equal(t, 1, count)
+}
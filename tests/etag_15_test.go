@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func requestIfMatch(t *testing.T, method, apipath, etag string, values map[string]any) *http.Response {
+	var data []byte
+	if values != nil {
+		var err error
+		data, err = json.Marshal(values)
+		assert.NoError(t, err)
+	}
+
+	req, err := http.NewRequest(method, getURL(apipath), bytes.NewBuffer(data))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestETag(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	id := addTask(t, task{date: date, title: "Согласовать макет", repeat: "d 2"})
+
+	resp := requestIfMatch(t, http.MethodGet, "api/task?id="+id, "", nil)
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	edit := map[string]any{"id": id, "date": date, "title": "Согласовать макет v2", "repeat": "d 2"}
+
+	if RequireIfMatch {
+		resp = requestIfMatch(t, http.MethodPut, "api/task", "", edit)
+		assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode)
+		resp = requestIfMatch(t, http.MethodPost, "api/task/done?id="+id, "", nil)
+		assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode)
+		resp = requestIfMatch(t, http.MethodDelete, "api/task?id="+id, "", nil)
+		assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode)
+	}
+
+	// Первая вкладка сохраняет изменения, вторая получает конфликт
+	resp = requestIfMatch(t, http.MethodPut, "api/task", etag, edit)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+	resp = requestIfMatch(t, http.MethodPut, "api/task", etag, edit)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp = requestIfMatch(t, http.MethodPost, "api/task/done?id="+id, etag, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp = requestIfMatch(t, http.MethodDelete, "api/task?id="+id, etag, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	var task Task
+	err := db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, task.Version)
+	assert.Equal(t, date, task.Date)
+
+	// Слабый ETag не подходит для If-Match
+	resp = requestIfMatch(t, http.MethodPost, "api/task/done?id="+id, `W/"2"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp = requestIfMatch(t, http.MethodPost, "api/task/done?id="+id, `"1", "2"`, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"3"`, resp.Header.Get("ETag"))
+
+	// Версия из списка задач подходит для If-Match без отдельного запроса задачи
+	body, err := requestJSON("api/tasks?search="+url.QueryEscape("Согласовать макет v2"), nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Tasks []map[string]string `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	var version string
+	for _, item := range list.Tasks {
+		if item["id"] == id {
+			version = item["version"]
+		}
+	}
+	assert.Equal(t, "3", version)
+
+	// Перенос в проект проверяет версию так же, как остальные изменения
+	if RequireIfMatch {
+		resp = requestIfMatch(t, http.MethodPost, "api/task/move?id="+id+"&project_id=", "", nil)
+		assert.Equal(t, http.StatusPreconditionRequired, resp.StatusCode)
+	}
+	resp = requestIfMatch(t, http.MethodPost, "api/task/move?id="+id+"&project_id=", `"2"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	resp = requestIfMatch(t, http.MethodPost, "api/task/move?id="+id+"&project_id=", `"`+version+`"`, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"4"`, resp.Header.Get("ETag"))
+
+	resp = requestIfMatch(t, http.MethodDelete, "api/task?id="+id, `"4"`, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	notFoundTask(t, id)
+}
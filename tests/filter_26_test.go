@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go_final_project/db"
+)
+
+func pageTitles(page taskPage) []string {
+	titles := []string{}
+	for _, task := range page.Tasks {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+func TestTaskFilters(t *testing.T) {
+	conn := openDB(t)
+	defer conn.Close()
+
+	_, err := conn.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	// Задачи добавляются напрямую, чтобы сохранить прошедшие даты
+	now := time.Now()
+	day := func(days int) string {
+		return now.AddDate(0, 0, days).Format(`20060102`)
+	}
+	for _, task := range []struct {
+		date, title, comment, repeat string
+	}{
+		{day(-2), "Просрочена", "", ""},
+		{day(-1), "Просрочена с повтором", "есть комментарий", "d 3"},
+		{day(0), "Сегодня", "", "y"},
+		{day(1), "Завтра", "комментарий", ""},
+		{day(5), "Через пять дней", "", "d 1"},
+	} {
+		_, err := conn.Exec("INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)",
+			task.date, task.title, task.comment, task.repeat)
+		assert.NoError(t, err)
+	}
+
+	for _, v := range []struct {
+		query  string
+		titles []string
+	}{
+		{"", []string{"Просрочена", "Просрочена с повтором", "Сегодня", "Завтра", "Через пять дней"}},
+		{"from=" + day(-1) + "&to=" + day(1), []string{"Просрочена с повтором", "Сегодня", "Завтра"}},
+		{"from=" + day(1), []string{"Завтра", "Через пять дней"}},
+		{"to=" + day(-1), []string{"Просрочена", "Просрочена с повтором"}},
+		{"overdue=1", []string{"Просрочена", "Просрочена с повтором"}},
+		{"repeat=none", []string{"Просрочена", "Завтра"}},
+		{"repeat=any", []string{"Просрочена с повтором", "Сегодня", "Через пять дней"}},
+		{"repeat=d", []string{"Просрочена с повтором", "Через пять дней"}},
+		{"repeat=y", []string{"Сегодня"}},
+		{"has_comment=1", []string{"Просрочена с повтором", "Завтра"}},
+		{"has_comment=0&repeat=any", []string{"Сегодня", "Через пять дней"}},
+		{"overdue=1&repeat=d&has_comment=1", []string{"Просрочена с повтором"}},
+	} {
+		page := getTaskPage(t, "total=1&"+v.query)
+		assert.Empty(t, page.Error, v.query)
+		assert.Equal(t, v.titles, pageTitles(page), v.query)
+		assert.Equal(t, len(v.titles), page.Total, v.query)
+	}
+
+	// total считает все подходящие задачи, а не только текущую страницу
+	page := getTaskPage(t, "total=1&repeat=any&limit=1")
+	assert.Equal(t, []string{"Просрочена с повтором"}, pageTitles(page))
+	assert.Equal(t, 3, page.Total)
+	assert.NotEmpty(t, page.NextCursor)
+
+	page = getTaskPage(t, "total=1&repeat=any&limit=1&cursor="+page.NextCursor)
+	assert.Equal(t, []string{"Сегодня"}, pageTitles(page))
+	assert.Equal(t, 3, page.Total)
+
+	// Без total=1 ответ сохраняет формат {"tasks": [...]}
+	ret, err := postJSON("api/tasks?repeat=any", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotContains(t, ret, "total")
+
+	// Устаревший заголовок X-Total-Count передаётся до следующего выпуска
+	resp, err := http.Get(getURL("api/tasks?repeat=any&limit=1"))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "3", resp.Header.Get("X-Total-Count"))
+
+	for _, query := range []string{
+		"from=2024-01-01", "to=abc", "from=" + day(1) + "&to=" + day(-1),
+		"repeat=w", "has_comment=yes", "overdue=true",
+	} {
+		ret, err := postJSON("api/tasks?"+query, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], "Ожидается ошибка для %s", query)
+	}
+
+	_, err = conn.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
+
+func TestTaskFilterIndex(t *testing.T) {
+	conn := openLargeDB(t, 1000)
+	defer conn.Close()
+
+	hasComment := false
+	filter := db.TaskFilter{Limit: 50, From: "20300110", To: "20300120", Repeat: "d", HasComment: &hasComment}
+	plan, err := db.ExplainTasks(context.Background(), conn, filter)
+	require.NoError(t, err)
+	assert.Contains(t, strings.Join(plan, "\n"), "idx_date")
+
+	total, err := db.CountTasks(context.Background(), conn, db.TaskFilter{From: "20300110", To: "20300120"})
+	require.NoError(t, err)
+	assert.Equal(t, 110, total)
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localizedError отправляет запрос с заголовком Accept-Language и cookie lang
+// (если они заданы) и возвращает язык ответа и тело ошибки
+func localizedError(t *testing.T, method, path, body, acceptLanguage, cookie string) (string, map[string]any) {
+	req, err := http.NewRequest(method, getURL(path), bytes.NewBufferString(body))
+	require.NoError(t, err)
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: "lang", Value: cookie})
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var ret map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	return resp.Header.Get("Content-Language"), ret
+}
+
+func TestErrorLanguage(t *testing.T) {
+	for _, v := range []struct {
+		acceptLanguage, cookie string
+		lang, message          string
+	}{
+		{"ru", "", "ru", "Задача не найдена"},
+		{"en-US,en;q=0.9", "", "en", "Task not found"},
+		{"fr-FR, ru;q=0.5, en;q=0.7", "", "en", "Task not found"},
+		{"de, en;q=0", "ru", "ru", "Задача не найдена"},
+		// Язык, выбранный пользователем, важнее заголовка
+		{"ru", "en", "en", "Task not found"},
+		{"en", "fr", "en", "Task not found"},
+	} {
+		lang, ret := localizedError(t, http.MethodGet, "api/task?id=999999", "", v.acceptLanguage, v.cookie)
+		assert.Equal(t, v.lang, lang, v)
+		assert.Equal(t, v.message, ret["error"], v)
+		assert.Equal(t, "task_not_found", ret["code"], v)
+	}
+
+	// Список задач использует тот же каталог
+	_, ret := localizedError(t, http.MethodGet, "api/tasks?cursor=oops", "", "ru", "")
+	assert.Equal(t, "invalid_cursor", ret["code"])
+	assert.Equal(t, "Некорректный курсор", ret["error"])
+	_, ret = localizedError(t, http.MethodGet, "api/tasks?cursor=oops", "", "en", "")
+	assert.Equal(t, "Invalid cursor", ret["error"])
+
+	// Сообщения с параметрами и ошибки отдельных операций пакетного запроса
+	conn := openDB(t)
+	defer conn.Close()
+	id := addTaskWithTags(t, "Язык ошибок", nil)
+	defer func() {
+		_, err := conn.Exec("DELETE FROM scheduler WHERE id = ?", id)
+		assert.NoError(t, err)
+	}()
+	_, ret = localizedError(t, http.MethodPatch, "api/task?id="+id, `{"unknown": 1}`, "en", "")
+	assert.Equal(t, "unknown_field", ret["code"])
+	assert.Equal(t, "Unknown field unknown", ret["error"])
+	body := `{"mode": "best_effort", "operations": [{"op": "delete", "id": "999999"}]}`
+	_, ret = localizedError(t, http.MethodPost, "api/tasks/batch", body, "en", "")
+	if results, ok := ret["results"].([]any); assert.True(t, ok) && assert.Len(t, results, 1) {
+		assert.Equal(t, "Task not found", results[0].(map[string]any)["error"])
+	}
+}
@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// importTasks отправляет файл в /api/import и возвращает код ответа и отчёт
+func importTasks(t *testing.T, query string, data []byte) (int, map[string]any) {
+	resp, err := http.Post(getURL("api/import?"+query), "application/octet-stream", bytes.NewReader(data))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var report map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	return resp.StatusCode, report
+}
+
+func exportTasks(t *testing.T, format string) []byte {
+	body, err := getBody("api/export?format=" + format)
+	assert.NoError(t, err)
+	return body
+}
+
+func TestImportExport(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	before, err := count(db)
+	assert.NoError(t, err)
+
+	id := addTaskWithTags(t, `Счёт, "срочно"`, []string{"финансы", "работа"})
+	defer func() {
+		_, err := db.Exec(`DELETE FROM scheduler WHERE title = ?`, `Счёт, "срочно"`)
+		assert.NoError(t, err)
+	}()
+
+	// JSON содержит все поля задачи
+	var exported struct {
+		Tasks []map[string]any `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(exportTasks(t, "json"), &exported))
+	assert.Len(t, exported.Tasks, before+1)
+	var found map[string]any
+	for _, task := range exported.Tasks {
+		if task["id"] == id {
+			found = task
+		}
+	}
+	if assert.NotNil(t, found) {
+		assert.Equal(t, []any{"работа", "финансы"}, found["tags"])
+		assert.NotEmpty(t, found["created_at"])
+	}
+
+	// CSV: заголовок и по строке на задачу, кавычки и запятые экранируются
+	rows, err := csv.NewReader(bytes.NewReader(exportTasks(t, "csv"))).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, rows, before+2)
+	assert.Equal(t, "id", rows[0][0])
+
+	// Проверка без записи
+	one := []byte("title,date,tags,priority\n" + `"Счёт, ""срочно""",20300101,"финансы,работа",2` + "\n")
+	code, report := importTasks(t, "format=csv&dry_run=1", one)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1.0, report["valid"])
+	assert.Equal(t, 0.0, report["imported"])
+	total, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before+1, total)
+
+	// Ошибки по строкам: ничего не импортируется
+	bad := []byte("title,date,repeat,created_at\n,20300101,,\nЗадача,2030-01-01,,\nЗадача,20000101,x 1,\n" +
+		"Задача,20300101,,01.01.2024\n")
+	code, report = importTasks(t, "format=csv", bad)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.NotEmpty(t, report["error"])
+	if errs, ok := report["errors"].([]any); assert.True(t, ok) && assert.Len(t, errs, 4) {
+		assert.Equal(t, 1.0, errs[0].(map[string]any)["row"])
+		assert.Equal(t, "invalid_created_at", errs[3].(map[string]any)["code"])
+	}
+	total, err = count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, before+1, total)
+
+	// Слияние добавляет задачи с новыми ID
+	code, report = importTasks(t, "format=csv&mode=merge", one)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1.0, report["imported"])
+	assert.Len(t, getTaskTitles(t, "tag=финансы"), 2)
+
+	// Замена выгруженным ранее файлом возвращает базу в исходное состояние с прежними ID
+	data := exportTasks(t, "json")
+	var snapshot struct {
+		Tasks []map[string]any `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &snapshot))
+	var keep []map[string]any
+	var dropped string
+	for _, task := range snapshot.Tasks {
+		if task["title"] != `Счёт, "срочно"` || task["id"] == id {
+			keep = append(keep, task)
+		} else {
+			dropped = task["id"].(string)
+		}
+	}
+	replace, err := json.Marshal(map[string]any{"tasks": keep})
+	assert.NoError(t, err)
+	code, report = importTasks(t, "format=json&mode=replace", replace)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(before+1), report["imported"])
+
+	var ids []string
+	assert.NoError(t, db.Select(&ids, `SELECT id FROM scheduler WHERE title = ?`, `Счёт, "срочно"`))
+	assert.Equal(t, []string{id}, ids)
+	// Замена записывает в журнал удаление прежних задач и добавление импортированных
+	if history := getHistory(t, dropped); assert.NotEmpty(t, history) {
+		assert.Equal(t, "delete", history[0]["action"])
+	}
+	if history := getHistory(t, id); assert.NotEmpty(t, history) {
+		assert.Equal(t, "create", history[0]["action"])
+	}
+	// Импорт сохраняет дату создания, а обычное добавление задачи - нет
+	var createdAt string
+	assert.NoError(t, db.Get(&createdAt, `SELECT created_at FROM scheduler WHERE id = ?`, id))
+	assert.Equal(t, found["created_at"], createdAt)
+	ret, err := postJSON("api/task", map[string]any{"title": "Без даты создания", "created_at": "давно"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Get(&createdAt, `SELECT created_at FROM scheduler WHERE id = ?`, ret["id"]))
+	_, err = time.Parse(time.RFC3339, createdAt)
+	assert.NoError(t, err)
+	_, err = db.Exec(`DELETE FROM scheduler WHERE id = ?`, ret["id"])
+	assert.NoError(t, err)
+	assert.Len(t, getTaskTitles(t, "tag=финансы"), 1)
+
+	// ID в разной записи совпадают в базе, поэтому это ошибка строки, а не сбой замены
+	clash, err := json.Marshal(map[string]any{"tasks": []map[string]any{
+		{"id": id, "date": found["date"], "title": "Первая"},
+		{"id": "0" + id, "date": found["date"], "title": "Вторая"},
+	}})
+	assert.NoError(t, err)
+	code, report = importTasks(t, "format=json&mode=replace", clash)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	if errs, ok := report["errors"].([]any); assert.True(t, ok) && assert.Len(t, errs, 1) {
+		assert.Equal(t, 2.0, errs[0].(map[string]any)["row"])
+		assert.Equal(t, "duplicate_task_id", errs[0].(map[string]any)["code"])
+	}
+	assert.NoError(t, db.Select(&ids, `SELECT id FROM scheduler WHERE title = ?`, `Счёт, "срочно"`))
+	assert.Equal(t, []string{id}, ids)
+
+	// Неизвестный формат
+	resp, err := http.Post(getURL("api/import?format=xml"), "text/xml", strings.NewReader("<tasks/>"))
+	assert.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
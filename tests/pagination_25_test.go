@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go_final_project/db"
+)
+
+type taskPage struct {
+	Tasks []struct {
+		ID    string `json:"id"`
+		Date  string `json:"date"`
+		Title string `json:"title"`
+	} `json:"tasks"`
+	NextCursor string `json:"next_cursor"`
+	Total      int    `json:"total"`
+	Error      string `json:"error"`
+}
+
+func getTaskPage(t *testing.T, query string) taskPage {
+	resp, err := http.Get(getURL("api/tasks?" + query))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var page taskPage
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	return page
+}
+
+func TestCursorPagination(t *testing.T) {
+	conn := openDB(t)
+	defer conn.Close()
+
+	_, err := conn.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	// Несколько задач на одну дату проверяют, что курсор различает их по ID
+	now := time.Now()
+	addTask := func(days int, title string) {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":  now.AddDate(0, 0, days).Format(`20060102`),
+			"title": title,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"])
+	}
+	for i, days := range []int{1, 2, 2, 2, 3, 4, 5} {
+		addTask(days, fmt.Sprintf("Задача %d", i))
+	}
+
+	var titles []string
+	seen := make(map[string]bool)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 10, "Листание не должно зацикливаться")
+
+		query := "limit=3"
+		if cursor != "" {
+			query += "&cursor=" + cursor
+		}
+		page := getTaskPage(t, query)
+		require.Empty(t, page.Error)
+		assert.LessOrEqual(t, len(page.Tasks), 3)
+		for _, task := range page.Tasks {
+			assert.False(t, seen[task.ID], "Задача %s повторяется", task.ID)
+			seen[task.ID] = true
+			titles = append(titles, task.Title)
+		}
+
+		// Задачи, добавленные во время листания перед курсором, не сдвигают страницы
+		if pages == 0 {
+			addTask(1, "Вставлена раньше курсора")
+			addTask(6, "Вставлена после курсора")
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"Задача 0", "Задача 1", "Задача 2", "Задача 3", "Задача 4",
+		"Задача 5", "Задача 6", "Вставлена после курсора"}, titles)
+
+	// Последняя полная страница не отдаёт курсор на пустую
+	page := getTaskPage(t, "limit=10")
+	assert.Len(t, page.Tasks, 9)
+	assert.Empty(t, page.NextCursor)
+
+	for _, query := range []string{"cursor=abc", "cursor=" + cursor + "&sort=title", "cursor=" + cursor + "&search=Задача"} {
+		page := getTaskPage(t, query)
+		assert.NotEmpty(t, page.Error, "Ожидается ошибка для %s", query)
+	}
+
+	_, err = conn.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
+
+// openLargeDB создаёт временную базу с n задачами
+func openLargeDB(tb testing.TB, n int) *db.DB {
+	path := filepath.Join(tb.TempDir(), "large.db")
+	require.NoError(tb, db.SetupDatabase(path))
+	conn, err := db.Open(path, db.DefaultConfig())
+	require.NoError(tb, err)
+
+	tx, err := conn.Begin()
+	require.NoError(tb, err)
+	stmt, err := tx.Prepare("INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, '', '')")
+	require.NoError(tb, err)
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		// По десять задач на дату
+		date := start.AddDate(0, 0, i/10).Format("20060102")
+		_, err := stmt.Exec(date, fmt.Sprintf("Задача %d", i))
+		require.NoError(tb, err)
+	}
+	require.NoError(tb, stmt.Close())
+	require.NoError(tb, tx.Commit())
+	return conn
+}
+
+func TestCursorIndex(t *testing.T) {
+	conn := openLargeDB(t, 1000)
+	defer conn.Close()
+
+	filter := db.TaskFilter{Limit: 50, After: &db.Cursor{Date: "20300301", ID: 600}}
+	plan, err := db.ExplainTasks(context.Background(), conn, filter)
+	require.NoError(t, err)
+
+	joined := strings.Join(plan, "\n")
+	assert.Contains(t, joined, "idx_date", "Страница должна выбираться по индексу даты")
+	assert.NotContains(t, joined, "TEMP B-TREE", "Сортировка должна браться из индекса")
+}
+
+// BenchmarkCursorPagination выбирает страницы из таблицы в 100 000 задач.
+// Время одной страницы не зависит от её номера, так как каждая начинается
+// с поиска по индексу idx_date, а не с пропуска OFFSET строк.
+func BenchmarkCursorPagination(b *testing.B) {
+	conn := openLargeDB(b, 100000)
+	defer conn.Close()
+	ctx := context.Background()
+
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.ResetTimer()
+	pages := 0
+	for i := 0; i < b.N; i++ {
+		// Страницы берутся из разных мест таблицы, в том числе из самого конца
+		id := 1 + (i*997)%99000
+		filter := db.TaskFilter{Limit: 50, After: &db.Cursor{
+			Date: start.AddDate(0, 0, (id-1)/10).Format("20060102"),
+			ID:   int64(id),
+		}}
+		_, next, err := db.ListTasksPage(ctx, conn, filter)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if next != nil {
+			pages++
+		}
+	}
+	b.ReportMetric(float64(pages)/float64(b.N), "full_pages/op")
+}
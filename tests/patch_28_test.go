@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// patchTask отправляет JSON Merge Patch в /api/task и возвращает код ответа, тело и ETag
+func patchTask(t *testing.T, id, body, contentType, ifMatch string) (int, map[string]any, string) {
+	req, err := http.NewRequest(http.MethodPatch, getURL("api/task?id="+id), strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var ret map[string]any
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	return resp.StatusCode, ret, resp.Header.Get("ETag")
+}
+
+func TestPatchTask(t *testing.T) {
+	conn := openDB(t)
+	defer conn.Close()
+
+	date := time.Now().AddDate(0, 0, 3).Format(`20060102`)
+	id := addTaskWithTags(t, "Исходная задача", []string{"дом"})
+	defer func() {
+		_, err := conn.Exec("DELETE FROM scheduler WHERE id = ?", id)
+		assert.NoError(t, err)
+	}()
+	_, err := conn.Exec("UPDATE scheduler SET date = ?, comment = ?, repeat = ?, priority = 2 WHERE id = ?",
+		date, "комментарий", "d 7", id)
+	require.NoError(t, err)
+
+	// Меняется только переданное поле, дата и остальные поля сохраняются
+	status, task, etag := patchTask(t, id, `{"title": "Новый заголовок"}`, "application/merge-patch+json", "")
+	require.Equal(t, http.StatusOK, status, task)
+	assert.Equal(t, "Новый заголовок", task["title"])
+	assert.Equal(t, date, task["date"])
+	assert.Equal(t, "комментарий", task["comment"])
+	assert.Equal(t, "d 7", task["repeat"])
+	assert.Equal(t, float64(2), task["priority"])
+	assert.Equal(t, []any{"дом"}, task["tags"])
+	assert.NotEmpty(t, etag)
+
+	// null сбрасывает поле
+	status, task, _ = patchTask(t, id, `{"comment": null, "tags": null, "priority": null}`, "application/merge-patch+json", etag)
+	require.Equal(t, http.StatusOK, status, task)
+	assert.Equal(t, "", task["comment"])
+	assert.Nil(t, task["tags"])
+	assert.Nil(t, task["priority"])
+	assert.Equal(t, "Новый заголовок", task["title"])
+	assert.Empty(t, getTaskTags(t, id))
+
+	// Устаревшая версия отклоняется
+	status, _, _ = patchTask(t, id, `{"title": "Другой"}`, "application/json", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+
+	status, task, _ = patchTask(t, id, `{"repeat": "d 2", "tags": ["Работа"]}`, "application/json", "")
+	require.Equal(t, http.StatusOK, status, task)
+	assert.Equal(t, "d 2", task["repeat"])
+	assert.Equal(t, []any{"работа"}, task["tags"])
+
+	for _, body := range []string{
+		`{"repeat": "ooops"}`,
+		`{"repeat": "d 500"}`,
+		`{"date": "2024-01-01"}`,
+		`{"date": null}`,
+		`{"title": null}`,
+		`{"title": ""}`,
+		`{"priority": 10}`,
+		`{"project_id": "999999"}`,
+		`{"created_at": "2024-01-01T00:00:00Z"}`,
+		`{"unknown": 1}`,
+		`{"id": "1", "title": "Чужая"}`,
+	} {
+		status, ret, _ := patchTask(t, id, body, "application/merge-patch+json", "")
+		assert.Equal(t, http.StatusUnprocessableEntity, status, body)
+		assert.NotEmpty(t, ret["error"], body)
+	}
+
+	status, _, _ = patchTask(t, id, `[]`, "application/merge-patch+json", "")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _, _ = patchTask(t, id, `{"title": "x"}`, "text/plain", "")
+	assert.Equal(t, http.StatusUnsupportedMediaType, status)
+
+	// Ошибочные патчи не изменили задачу
+	var stored struct {
+		Title  string `db:"title"`
+		Repeat string `db:"repeat"`
+		Date   string `db:"date"`
+	}
+	require.NoError(t, conn.Get(&stored, "SELECT title, repeat, date FROM scheduler WHERE id = ?", id))
+	assert.Equal(t, "Новый заголовок", stored.Title)
+	assert.Equal(t, "d 2", stored.Repeat)
+	assert.Equal(t, date, stored.Date)
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addProject(t *testing.T, name string) string {
+	ret, err := postJSON("api/project", map[string]any{
+		"name":  name,
+		"color": "#3366ff",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, id)
+	return id
+}
+
+func TestProjects(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	work := addProject(t, "Работа")
+	home := addProject(t, "Дом")
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":       date,
+		"title":      "Отчёт",
+		"project_id": work,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	report := fmt.Sprint(ret["id"])
+	addTask(t, task{date: date, title: "Без проекта"})
+
+	assert.Equal(t, []string{"Отчёт"}, getTaskTitles(t, "project_id="+work))
+	assert.Equal(t, []string{"Без проекта"}, getTaskTitles(t, "project_id=none"))
+
+	// Перенос задачи между проектами
+	ret, err = postJSON("api/task/move?id="+report+"&project_id="+home, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Empty(t, getTaskTitles(t, "project_id="+work))
+	assert.Equal(t, []string{"Отчёт"}, getTaskTitles(t, "project_id="+home))
+
+	// Задачи архивного проекта скрыты из общего списка
+	ret, err = postJSON("api/project", map[string]any{
+		"id":       home,
+		"name":     "Дом",
+		"archived": true,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, []string{"Без проекта"}, getTaskTitles(t, ""))
+	assert.Len(t, getTaskTitles(t, "archived=1"), 2)
+
+	ret, err = postJSON("api/task", map[string]any{
+		"date":       date,
+		"title":      "В архив",
+		"project_id": home,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// Каскадное удаление проекта удаляет его задачи
+	ret, err = postJSON("api/project?id="+home+"&mode=cascade", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, report)
+	// Удаление задачи вместе с проектом записывается в журнал
+	if records := getHistory(t, report); assert.NotEmpty(t, records) {
+		assert.Equal(t, "delete", records[0]["action"])
+		assert.NotNil(t, records[0]["before"])
+	}
+
+	ret, err = postJSON("api/project?id="+work, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, []string{"Без проекта"}, getTaskTitles(t, "archived=1"))
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	addTask(t, task{date: date, title: "Купить молоко", comment: "и хлеб"})
+	addTask(t, task{date: date, title: "Позвонить маме", comment: "спросить про молоко и творог"})
+	addTask(t, task{date: date, title: "Молоко, молоко, молоко"})
+
+	// Результаты упорядочены по релевантности
+	assert.Equal(t, []string{"Молоко, молоко, молоко", "Купить молоко", "Позвонить маме"},
+		getTaskTitles(t, "search=молоко"))
+	// Слова ищутся как префиксы, все слова обязательны
+	assert.Equal(t, []string{"Позвонить маме"}, getTaskTitles(t, "search=мол%20твор"))
+	// Явная сортировка отменяет упорядочивание по релевантности
+	assert.Equal(t, []string{"Купить молоко", "Молоко, молоко, молоко", "Позвонить маме"},
+		getTaskTitles(t, "search=молоко&sort=title"))
+
+	// Синтаксис FTS5 в строке поиска не интерпретируется
+	for _, search := range []string{`"`, `OR`, `молоко*`, `title:молоко`, `(`} {
+		ret, err := postJSON("api/tasks?search="+search, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"], "Неожиданная ошибка для search=%s", search)
+	}
+
+	// После редактирования индекс обновляется
+	tasks := getTasks(t, "хлеб")
+	assert.Len(t, tasks, 1)
+	if len(tasks) == 1 {
+		ret, err := postJSON("api/task", map[string]any{
+			"id":    tasks[0]["id"],
+			"date":  date,
+			"title": "Купить кефир",
+		}, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+		assert.Empty(t, getTasks(t, "хлеб"))
+		assert.Len(t, getTasks(t, "кефир"), 1)
+	}
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
+
+func TestSearchCyrillic(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	addTask(t, task{date: date, title: "Купить Ёлку"})
+	addTask(t, task{date: date, title: "ПОЧИНИТЬ iPhone", comment: "Сервис на Арбате"})
+	addTask(t, task{date: date, title: "Обед в Café Пушкинъ"})
+	addTask(t, task{date: date, title: "Ежедневный отчёт"})
+
+	for search, want := range map[string][]string{
+		"купить":            {"Купить Ёлку"},
+		"КУПИТЬ":            {"Купить Ёлку"},
+		"елку":              {"Купить Ёлку"},
+		"ЁЛК":               {"Купить Ёлку"},
+		"починить%20IPHONE": {"ПОЧИНИТЬ iPhone"},
+		"арбат":             {"ПОЧИНИТЬ iPhone"},
+		"cafe%20пушкин":     {"Обед в Café Пушкинъ"},
+		"CAFÉ":              {"Обед в Café Пушкинъ"},
+		"отчет":             {"Ежедневный отчёт"},
+		"ЁЖЕДНЕВНЫЙ":        {"Ежедневный отчёт"},
+	} {
+		assert.Equal(t, want, getTaskTitles(t, "search="+search), "search=%s", search)
+	}
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
@@ -3,5 +3,7 @@ package tests
 var Port = 7540
 var DBFile = "../scheduler.db"
 var FullNextDate = false
-var Search = false
+var Search = true
 var Token = ``
+var CalendarToken = ``
+var RequireIfMatch = false
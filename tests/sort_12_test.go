@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSort(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	ids := map[string]string{}
+	for _, v := range []struct {
+		days     int
+		title    string
+		priority int
+	}{
+		{1, "Б", 1},
+		{2, "А", 3},
+		{1, "В", 3},
+	} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":     now.AddDate(0, 0, v.days).Format(`20060102`),
+			"title":    v.title,
+			"priority": v.priority,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"])
+		ids[v.title] = fmt.Sprint(ret["id"])
+	}
+
+	assert.Equal(t, []string{"Б", "В", "А"}, getTaskTitles(t, ""))
+	assert.Equal(t, []string{"В", "А", "Б"}, getTaskTitles(t, "sort=-priority,date"))
+	assert.Equal(t, []string{"А", "Б", "В"}, getTaskTitles(t, "sort=title"))
+	assert.Equal(t, []string{"А", "В", "Б"}, getTaskTitles(t, "sort=-priority,-date"))
+
+	for _, sort := range []string{"id", "date%20desc", "priority,priority", "-"} {
+		ret, err := postJSON("api/tasks?sort="+sort, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], "Ожидается ошибка для sort=%s", sort)
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"title":    "Слишком важно",
+		"priority": 10,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// Изменение без поля priority сохраняет приоритет, явный 0 сбрасывает его
+	date := now.AddDate(0, 0, 1).Format(`20060102`)
+	for _, v := range []struct {
+		values   map[string]any
+		priority int
+	}{
+		{map[string]any{"id": ids["Б"], "date": date, "title": "Б"}, 1},
+		{map[string]any{"id": ids["Б"], "date": date, "title": "Б", "priority": 0}, 0},
+	} {
+		ret, err = postJSON("api/task", v.values, http.MethodPut)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+		var priority int
+		assert.NoError(t, db.Get(&priority, "SELECT priority FROM scheduler WHERE id = ?", ids["Б"]))
+		assert.Equal(t, v.priority, priority, v.values)
+	}
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go_final_project/db"
+	"go_final_project/models"
+)
+
+func TestSQLiteConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sqlite.db")
+	require.NoError(t, db.SetupDatabase(path))
+
+	cfg := db.DefaultConfig()
+	cfg.JournalMode = "UNKNOWN"
+	_, err := db.Open(path, cfg)
+	assert.Error(t, err)
+
+	cfg = db.DefaultConfig()
+	cfg.BusyTimeout = time.Millisecond
+	cfg.BusyRetries = 0
+	conn, err := db.Open(path, cfg)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Прагмы применяются к каждому соединению пула, а не только к первому
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		c, err := conn.Conn(ctx)
+		require.NoError(t, err)
+		defer c.Close()
+
+		var journal string
+		var foreignKeys, busyTimeout int
+		require.NoError(t, c.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journal))
+		require.NoError(t, c.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys))
+		require.NoError(t, c.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout))
+		assert.Equal(t, "wal", journal)
+		assert.Equal(t, 1, foreignKeys)
+		assert.Equal(t, 1, busyTimeout)
+	}
+
+	// Другой процесс держит блокировку записи
+	other, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer other.Close()
+	lock, err := other.Conn(ctx)
+	require.NoError(t, err)
+	defer lock.Close()
+	_, err = lock.ExecContext(ctx, "BEGIN IMMEDIATE")
+	require.NoError(t, err)
+
+	// Без повторов запрос сразу получает ErrBusy
+	task := models.Task{Date: "20300101", Title: "Проверка блокировки"}
+	_, err = db.AddTask(ctx, conn, task)
+	assert.ErrorIs(t, err, db.ErrBusy)
+
+	// С повторами запрос дожидается снятия блокировки
+	cfg.BusyRetries = db.DefaultBusyRetries
+	retrying, err := db.Open(path, cfg)
+	require.NoError(t, err)
+	defer retrying.Close()
+
+	// Настройки повторов относятся к подключению: первое по-прежнему их не делает
+	_, err = db.AddTask(ctx, conn, task)
+	assert.ErrorIs(t, err, db.ErrBusy)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lock.ExecContext(ctx, "COMMIT")
+	}()
+	id, err := db.AddTask(ctx, retrying, task)
+	require.NoError(t, err)
+
+	got, err := db.GetTaskByID(ctx, retrying, int(id))
+	require.NoError(t, err)
+	assert.Equal(t, task.Title, got.Title)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getSubtasks(t *testing.T, taskID string) []map[string]any {
+	body, err := requestJSON("api/subtasks?task_id="+taskID, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["subtasks"]
+}
+
+func TestSubtasks(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		date:   time.Now().Format(`20060102`),
+		title:  "Собрать чемодан",
+		repeat: "d 7",
+	})
+
+	var ids []string
+	for _, title := range []string{"Паспорт", "Зарядка", "Зонт"} {
+		ret, err := postJSON("api/subtask", map[string]any{
+			"task_id": id,
+			"title":   title,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	ret, err := postJSON("api/subtask/toggle?id="+ids[0], nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret["done"])
+
+	ret, err = postJSON("api/subtasks/reorder", map[string]any{
+		"task_id": id,
+		"ids":     []string{ids[2], ids[0], ids[1]},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	subtasks := getSubtasks(t, id)
+	assert.Len(t, subtasks, 3)
+	if len(subtasks) == 3 {
+		assert.Equal(t, "Зонт", subtasks[0]["title"])
+		assert.Equal(t, "Паспорт", subtasks[1]["title"])
+		assert.Equal(t, true, subtasks[1]["done"])
+	}
+
+	// Выполнение повторяющейся задачи сбрасывает чек-лист
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	for _, subtask := range getSubtasks(t, id) {
+		assert.Equal(t, false, subtask["done"])
+	}
+
+	ret, err = postJSON("api/subtask?id="+ids[1], nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Len(t, getSubtasks(t, id), 2)
+
+	// Повторяющийся ID не подходит, даже если число элементов совпадает
+	ret, err = postJSON("api/subtasks/reorder", map[string]any{
+		"task_id": id,
+		"ids":     []string{ids[0], ids[0]},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	subtasks = getSubtasks(t, id)
+	if assert.Len(t, subtasks, 2) {
+		assert.Equal(t, "Зонт", subtasks[0]["title"])
+		assert.Equal(t, "Паспорт", subtasks[1]["title"])
+	}
+
+	// Удаление задачи удаляет её подзадачи
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Empty(t, getSubtasks(t, id))
+}
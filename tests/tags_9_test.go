@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addTaskWithTags(t *testing.T, title string, tags []string) string {
+	ret, err := postJSON("api/task", map[string]any{
+		"date":  time.Now().AddDate(0, 0, 1).Format(`20060102`),
+		"title": title,
+		"tags":  tags,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(ret["id"])
+	assert.NotEmpty(t, id)
+	return id
+}
+
+func getTaskTitles(t *testing.T, query string) []string {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]any
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+
+	titles := []string{}
+	for _, task := range m["tasks"] {
+		titles = append(titles, fmt.Sprint(task["title"]))
+	}
+	return titles
+}
+
+func TestTags(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	addTaskWithTags(t, "Отчёт", []string{"Work", "urgent"})
+	addTaskWithTags(t, "Созвон", []string{"work"})
+	id := addTaskWithTags(t, "Уборка", []string{"home"})
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, []any{"home"}, m["tags"])
+
+	assert.ElementsMatch(t, []string{"Отчёт", "Созвон"}, getTaskTitles(t, "tag=work"))
+	assert.ElementsMatch(t, []string{"Отчёт", "Уборка"}, getTaskTitles(t, "tags=urgent,home"))
+	assert.ElementsMatch(t, []string{"Отчёт"}, getTaskTitles(t, "tags=urgent,work&tag_mode=and"))
+
+	// Пустой массив очищает теги, отсутствие поля оставляет их без изменений
+	ret, err := postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  time.Now().AddDate(0, 0, 1).Format(`20060102`),
+		"title": "Уборка",
+		"tags":  []string{},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Empty(t, getTaskTitles(t, "tag=home"))
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
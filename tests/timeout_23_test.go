@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_final_project/db"
+	"go_final_project/models"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeout.db")
+	assert.NoError(t, db.SetupDatabase(path))
+	conn, err := db.Open(path, db.DefaultConfig())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	task := models.Task{Date: "20300101", Title: "Проверка таймаута"}
+	id, err := db.AddTask(context.Background(), conn, task)
+	assert.NoError(t, err)
+
+	// Отменённый запрос клиента прерывает обращение к базе
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = db.GetTaskByID(ctx, conn, int(id))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Ограничение времени запроса действует, даже если у контекста клиента его нет
+	conn.SetQueryTimeout(time.Nanosecond)
+	_, err = db.ListTasks(context.Background(), conn, db.TaskFilter{Limit: -1})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = db.AddTask(context.Background(), conn, task)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	conn.SetQueryTimeout(0)
+	got, err := db.GetTaskByID(context.Background(), conn, int(id))
+	assert.NoError(t, err)
+	assert.Equal(t, task.Title, got.Title)
+}
@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"go_final_project/models"
+	"go_final_project/todotxt"
+)
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	tbl := []struct {
+		task    models.Task
+		project string
+		line    string
+	}{
+		{
+			models.Task{Title: "Купить молоко", Date: "20300102"},
+			"",
+			"Купить молоко due:2030-01-02",
+		},
+		{
+			models.Task{Title: "Позвонить маме", Date: "20300105", Repeat: "d 7", Priority: 3,
+				Tags: []string{"мобильный телефон"}, CreatedAt: "2026-10-01T00:00:00Z"},
+			"Семья",
+			"(A) 2026-10-01 Позвонить маме +Семья @мобильный_телефон due:2030-01-05 rec:7d",
+		},
+		{
+			models.Task{Title: "День рождения time:10", Date: "20300310", Repeat: "y", Priority: 1},
+			"Личные дела",
+			"(C) День рождения time:10 +Личные_дела due:2030-03-10 rec:1y",
+		},
+		{
+			// Подчёркивание и процент в названии сохраняются
+			models.Task{Title: "Разобрать почту", Date: "20300101", Tags: []string{"my_tag", "100% важно"}},
+			"snake_case",
+			"Разобрать почту +snake%5Fcase @my%5Ftag @100%25_важно due:2030-01-01",
+		},
+		{
+			// Слова заголовка, похожие на проект, тег или расширение, экранируются
+			models.Task{Title: "+5 к карме @дома due:завтра rec:никогда %41 100%", Date: "20300101"},
+			"",
+			"%2B5 к карме %40дома %64ue:завтра %72ec:никогда %2541 100% due:2030-01-01",
+		},
+		{
+			// В начале заголовка - также отметка о выполнении, приоритет и дата
+			models.Task{Title: "x (A) 2030-01-01", Date: "20300101"},
+			"",
+			"%78 (A) 2030-01-01 due:2030-01-01",
+		},
+		{
+			models.Task{Title: "(A) приоритет в заголовке", Date: "20300101", Priority: 2},
+			"",
+			"(B) %28A) приоритет в заголовке due:2030-01-01",
+		},
+		{
+			models.Task{Title: "2030-01-01 план", Date: "20300101"},
+			"",
+			"%32030-01-01 план due:2030-01-01",
+		},
+	}
+	for _, v := range tbl {
+		line := todotxt.Format(v.task, v.project)
+		assert.Equal(t, v.line, line)
+
+		item, err := todotxt.Parse(line)
+		assert.NoError(t, err)
+		assert.Equal(t, v.task, item.Task)
+		assert.Empty(t, item.Unconverted)
+		if v.project != "" {
+			assert.Equal(t, []string{v.project}, item.Projects)
+		}
+	}
+
+	item, err := todotxt.Parse("x 2026-10-10 2026-10-01 Старое дело")
+	assert.NoError(t, err)
+	assert.True(t, item.Done)
+	assert.Equal(t, "20261010", item.Completed)
+	assert.Equal(t, "Старое дело", item.Task.Title)
+
+	item, err = todotxt.Parse("(D) Отчёт rec:+2w")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, item.Task.Priority)
+	assert.Equal(t, "d 14", item.Task.Repeat)
+
+	item, err = todotxt.Parse("Отчёт rec:1m")
+	assert.NoError(t, err)
+	assert.Empty(t, item.Task.Repeat)
+	assert.Equal(t, "rec:1m", item.Unconverted)
+
+	_, err = todotxt.Parse("Отчёт due:2030-13-01")
+	assert.Error(t, err)
+}
+
+func TestTodoTxt(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 3)
+	due := date.Format("2006-01-02")
+	data := strings.Join([]string{
+		"(B) todo.txt: позвонить +Тестовый_проект @телефон due:" + due + " rec:3d",
+		"x todo.txt: уже сделано",
+		"todo.txt: без проекта due:" + due,
+		"",
+		"@телефон due:" + due,
+	}, "\n")
+
+	resp, err := http.Post(getURL("api/todotxt?dry_run=1"), "text/plain", strings.NewReader(data))
+	assert.NoError(t, err)
+	var report struct {
+		DryRun   bool `json:"dry_run"`
+		Total    int  `json:"total"`
+		Imported int  `json:"imported"`
+		Skipped  int  `json:"skipped"`
+		Failed   int  `json:"failed"`
+		Items    []struct {
+			Line   int    `json:"line"`
+			Status string `json:"status"`
+			ID     string `json:"id"`
+		} `json:"items"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	resp.Body.Close()
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 1, report.Failed)
+	if assert.Len(t, report.Items, 4) {
+		assert.Equal(t, 5, report.Items[3].Line)
+		assert.Empty(t, report.Items[0].ID)
+	}
+
+	resp, err = http.Post(getURL("api/todotxt"), "text/plain", strings.NewReader(data))
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	resp.Body.Close()
+	assert.False(t, report.DryRun)
+	assert.Equal(t, 2, report.Imported)
+	defer func() {
+		_, err := db.Exec(`DELETE FROM scheduler WHERE title LIKE 'todo.txt:%'`)
+		assert.NoError(t, err)
+		_, err = db.Exec(`DELETE FROM projects WHERE name = 'Тестовый проект'`)
+		assert.NoError(t, err)
+	}()
+
+	// Проект создан при импорте и виден в выгрузке
+	var projectID int64
+	assert.NoError(t, db.Get(&projectID, `SELECT id FROM projects WHERE name = 'Тестовый проект'`))
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id = ?`, report.Items[0].ID))
+	assert.Equal(t, projectID, task.ProjectID.Int64)
+	assert.Equal(t, "d 3", task.Repeat)
+	assert.Equal(t, 2, task.Priority)
+	assert.Equal(t, date.Format(`20060102`), task.Date)
+
+	body, err := getBody("api/todotxt")
+	assert.NoError(t, err)
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), "todo.txt:") {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	created := time.Now().UTC().Format("2006-01-02")
+	assert.Equal(t, []string{
+		"(B) " + created + " todo.txt: позвонить +Тестовый_проект @телефон due:" + due + " rec:3d",
+		created + " todo.txt: без проекта due:" + due,
+	}, lines)
+
+	// Архивный проект находится по названию и не создаётся повторно:
+	// задачу в него не добавить
+	_, err = db.Exec(`INSERT INTO projects (name, archived) VALUES ('Архив todo.txt', 1)`)
+	assert.NoError(t, err)
+	defer func() {
+		_, err := db.Exec(`DELETE FROM projects WHERE name = 'Архив todo.txt'`)
+		assert.NoError(t, err)
+	}()
+	resp, err = http.Post(getURL("api/todotxt"), "text/plain",
+		strings.NewReader("todo.txt: в архив +архив_TODO.TXT due:"+due))
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	resp.Body.Close()
+	assert.Equal(t, 0, report.Imported)
+	assert.Equal(t, 1, report.Failed)
+	var projects int
+	assert.NoError(t, db.Get(&projects, `SELECT COUNT(*) FROM projects WHERE name LIKE 'Архив todo.txt'`))
+	assert.Equal(t, 1, projects)
+}
@@ -0,0 +1,258 @@
+// Package todotxt переводит задачи в строки формата todo.txt и обратно.
+//
+// Соответствие полей:
+//   - приоритет (A), (B), (C) - приоритет задачи 3, 2, 1;
+//   - дата создания - дата из created_at;
+//   - +проект - название проекта, @контекст - тег (пробелы заменяются на "_",
+//     а сами "_" и "%" записываются как %5F и %25);
+//   - due:ГГГГ-ММ-ДД - дата задачи, rec:Nd, rec:Nw, rec:1y - правило повторения.
+//
+// Слова заголовка, которые при разборе были бы приняты за проект, тег,
+// расширение, отметку о выполнении, приоритет или дату, записываются
+// с первым символом в виде %XX (например, %2B5 вместо +5).
+//
+// Комментарий задачи в todo.txt не переносится.
+package todotxt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go_final_project/constants"
+	"go_final_project/models"
+)
+
+// dateFormat - формат дат в todo.txt
+const dateFormat = "2006-01-02"
+
+// priorities - буквы приоритетов по убыванию важности
+const priorities = "ABC"
+
+// Item - задача, прочитанная из строки todo.txt
+type Item struct {
+	Task models.Task
+	// Done и Completed - отметка о выполнении и её дата (YYYYMMDD)
+	Done      bool
+	Completed string
+	// Projects - названия проектов из +проект; у задачи может быть только один
+	Projects []string
+	// Unconverted - правило rec:, которое не удалось перевести в повторение задачи
+	Unconverted string
+}
+
+// Format возвращает строку todo.txt для задачи; project - название её проекта
+func Format(task models.Task, project string) string {
+	var parts []string
+	if task.Priority > 0 && task.Priority <= len(priorities) {
+		letter := priorities[len(priorities)-task.Priority]
+		parts = append(parts, "("+string(letter)+")")
+	}
+	if created, err := time.Parse(time.RFC3339, task.CreatedAt); err == nil {
+		parts = append(parts, created.UTC().Format(dateFormat))
+	}
+
+	for i, word := range strings.Fields(task.Title) {
+		parts = append(parts, escapeWord(word, i == 0))
+	}
+	if project != "" {
+		parts = append(parts, "+"+encodeName(project))
+	}
+	for _, tag := range task.Tags {
+		parts = append(parts, "@"+encodeName(tag))
+	}
+
+	if date, err := time.Parse(constants.DateFormat, task.Date); err == nil {
+		parts = append(parts, "due:"+date.Format(dateFormat))
+	}
+	if rec, ok := RepeatToRec(task.Repeat); ok {
+		parts = append(parts, "rec:"+rec)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Parse разбирает строку todo.txt.
+// Неизвестные расширения вида ключ:значение остаются в заголовке задачи.
+func Parse(line string) (Item, error) {
+	var item Item
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return item, errors.New("empty line")
+	}
+
+	if words[0] == "x" {
+		item.Done = true
+		words = words[1:]
+		if len(words) > 0 {
+			if date, ok := parseDate(words[0]); ok {
+				item.Completed = date
+				words = words[1:]
+			}
+		}
+	} else if isPriority(words[0]) {
+		item.Task.Priority = letterPriority(words[0][1])
+		words = words[1:]
+	}
+
+	if len(words) > 0 {
+		if date, ok := parseDate(words[0]); ok {
+			created, _ := time.Parse(constants.DateFormat, date)
+			item.Task.CreatedAt = created.Format(time.RFC3339)
+			words = words[1:]
+		}
+	}
+
+	var title []string
+	for _, word := range words {
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			item.Projects = append(item.Projects, decodeName(word[1:]))
+		case len(word) > 1 && word[0] == '@':
+			item.Task.Tags = append(item.Task.Tags, decodeName(word[1:]))
+		case strings.HasPrefix(word, "due:"):
+			date, ok := parseDate(strings.TrimPrefix(word, "due:"))
+			if !ok {
+				return item, fmt.Errorf("invalid due date %q", word)
+			}
+			item.Task.Date = date
+		case strings.HasPrefix(word, "rec:"):
+			repeat, err := RecToRepeat(strings.TrimPrefix(word, "rec:"))
+			if err != nil {
+				item.Unconverted = word
+			}
+			item.Task.Repeat = repeat
+		default:
+			title = append(title, unescapeWord(word))
+		}
+	}
+	item.Task.Title = strings.Join(title, " ")
+	return item, nil
+}
+
+// RepeatToRec переводит правило повторения задачи в значение расширения rec:
+func RepeatToRec(repeat string) (string, bool) {
+	parts := strings.Fields(repeat)
+	switch {
+	case len(parts) == 2 && parts[0] == "d":
+		days, err := strconv.Atoi(parts[1])
+		if err != nil || days <= 0 {
+			return "", false
+		}
+		return strconv.Itoa(days) + "d", true
+	case len(parts) == 1 && parts[0] == "y":
+		return "1y", true
+	}
+	return "", false
+}
+
+// RecToRepeat переводит значение расширения rec: (например, 3d, +2w, 1y) в правило
+// повторения задачи. Месяцы (m) и рабочие дни (b) не поддерживаются.
+// Плюс (повтор от срока, а не от даты выполнения) отбрасывается: задачи всегда
+// повторяются от своей даты.
+func RecToRepeat(rec string) (string, error) {
+	rec = strings.TrimPrefix(rec, "+")
+	if rec == "" {
+		return "", errors.New("empty rule")
+	}
+
+	unit := rec[len(rec)-1]
+	n := 1
+	if number := rec[:len(rec)-1]; number != "" {
+		var err error
+		n, err = strconv.Atoi(number)
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid interval %q", number)
+		}
+	}
+
+	var days int
+	switch unit {
+	case 'd':
+		days = n
+	case 'w':
+		days = 7 * n
+	case 'y':
+		if n != 1 {
+			return "", errors.New("only yearly rules without interval are supported")
+		}
+		return "y", nil
+	default:
+		return "", fmt.Errorf("unit %q is not supported", string(unit))
+	}
+
+	if days > 400 {
+		return "", fmt.Errorf("interval of %d days exceeds 400", days)
+	}
+	return "d " + strconv.Itoa(days), nil
+}
+
+// letterPriority переводит букву приоритета в приоритет задачи; все буквы после C - низший
+func letterPriority(letter byte) int {
+	if i := strings.IndexByte(priorities, letter); i >= 0 {
+		return len(priorities) - i
+	}
+	return 1
+}
+
+// isPriority сообщает, что слово - приоритет вида (A)
+func isPriority(word string) bool {
+	return len(word) == 3 && word[0] == '(' && word[2] == ')' && word[1] >= 'A' && word[1] <= 'Z'
+}
+
+// parseDate разбирает дату ГГГГ-ММ-ДД и возвращает её в формате задачи
+func parseDate(s string) (string, bool) {
+	date, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return "", false
+	}
+	return date.Format(constants.DateFormat), true
+}
+
+// nameEncoder заменяет пробелы в названиях проектов и тегов, так как в todo.txt
+// слова разделяются пробелами. Собственные "_" названия экранируются, чтобы
+// после разбора тег my_tag не превратился в "my tag".
+var nameEncoder = strings.NewReplacer("%", "%25", "_", "%5F", " ", "_")
+
+// nameDecoder отменяет замены nameEncoder
+var nameDecoder = strings.NewReplacer("_", " ", "%5F", "_", "%25", "%")
+
+// encodeName возвращает название проекта или тега в виде одного слова todo.txt
+func encodeName(name string) string {
+	return nameEncoder.Replace(name)
+}
+
+// decodeName восстанавливает название, записанное encodeName
+func decodeName(name string) string {
+	return nameDecoder.Replace(name)
+}
+
+// escapeWord экранирует слово заголовка, которое Parse иначе не оставил бы
+// в заголовке; first - слово стоит первым в заголовке, где Parse ищет также
+// отметку о выполнении, приоритет и дату создания. Слова, начинающиеся с "%",
+// экранируются всегда, чтобы их не приняли за экранированные.
+func escapeWord(word string, first bool) string {
+	special := word[0] == '%' ||
+		len(word) > 1 && (word[0] == '+' || word[0] == '@') ||
+		strings.HasPrefix(word, "due:") || strings.HasPrefix(word, "rec:")
+	if first && !special {
+		_, date := parseDate(word)
+		special = word == "x" || isPriority(word) || date
+	}
+	if !special {
+		return word
+	}
+	return fmt.Sprintf("%%%02X", word[0]) + word[1:]
+}
+
+// unescapeWord отменяет замену, сделанную escapeWord
+func unescapeWord(word string) string {
+	if len(word) >= 3 && word[0] == '%' {
+		if b, err := strconv.ParseUint(word[1:3], 16, 8); err == nil && b < utf8.RuneSelf {
+			return string(rune(b)) + word[3:]
+		}
+	}
+	return word
+}
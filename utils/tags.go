@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxTagLength - максимальная длина тега в символах
+const MaxTagLength = 32
+
+// NormalizeTags приводит теги к нижнему регистру, убирает пробелы и дубликаты.
+// Запятая в теге запрещена, так как используется как разделитель в запросах.
+func NormalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, errors.New("empty tag")
+		}
+		if strings.Contains(tag, ",") {
+			return nil, errors.New("tag must not contain commas")
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, errors.New("tag is too long")
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,58 @@
+// Передача версии задачи в заголовке If-Match.
+// Версия запоминается при чтении задачи: из ETag карточки задачи или из поля
+// version списка задач. Она отправляется при изменении, завершении и удалении задачи:
+// если задачу успели изменить в другой вкладке, сервер ответит 412
+// и изменения не затрут чужие.
+(function () {
+    const etags = {};
+
+    // taskRequest возвращает ID задачи, если запрос относится к /api/task
+    function taskRequest(config) {
+        const match = /^\/?api\/task(\/done)?(\?id=(\d+))?$/.exec(config.url || "");
+        if (!match) {
+            return "";
+        }
+        if (match[3]) {
+            return match[3];
+        }
+        return config.data && config.data.id ? String(config.data.id) : "";
+    }
+
+    // listRequest сообщает, что запрос возвращает список задач
+    function listRequest(config) {
+        return /^\/?api\/tasks(\?|$)/.test(config.url || "");
+    }
+
+    axios.interceptors.request.use(function (config) {
+        const id = taskRequest(config);
+        if (id && config.method !== "get" && etags[id]) {
+            config.headers["If-Match"] = etags[id];
+        }
+        return config;
+    });
+
+    axios.interceptors.response.use(function (resp) {
+        if (listRequest(resp.config) && resp.data && resp.data.tasks) {
+            resp.data.tasks.forEach(function (task) {
+                etags[task.id] = '"' + task.version + '"';
+            });
+            return resp;
+        }
+
+        const id = taskRequest(resp.config);
+        if (id) {
+            if (resp.config.method === "delete" || !resp.headers.etag) {
+                delete etags[id];
+            } else {
+                etags[id] = resp.headers.etag;
+            }
+        }
+        return resp;
+    }, function (err) {
+        // Устаревшую версию нужно перечитать
+        if (err.config) {
+            delete etags[taskRequest(err.config)];
+        }
+        return Promise.reject(err);
+    });
+})();